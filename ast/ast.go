@@ -0,0 +1,342 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ricochhet/gomake/expr"
+	"github.com/ricochhet/gomake/token"
+	"github.com/ricochhet/gomake/util"
+)
+
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// Parameter types. A parameter without a type accepts any string.
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeEnum   = "enum"
+)
+
+type Node interface {
+	Position() token.Position
+}
+
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+type File struct {
+	Name     string      `json:"name"`
+	Vars     []*VarDecl  `json:"vars"`
+	Tasks    []*TaskDecl `json:"tasks"`
+	Comments []*Comment  `json:"comments"`
+}
+
+// VarDecl declares a variable at file level or inside a task. The value is
+// expanded each time the variable is referenced as ${NAME}.
+type VarDecl struct {
+	Pos   token.Position `json:"pos"`
+	Name  string         `json:"name"`
+	Value string         `json:"value"`
+}
+
+// TaskDecl is a task declaration. Needs lists the tasks declared with
+// needs(...) which run at most once per invocation before the body. Doc is
+// the text of the comment lines immediately preceding the declaration.
+type TaskDecl struct {
+	Pos    token.Position `json:"pos"`
+	Name   string         `json:"name"`
+	Doc    string         `json:"doc"`
+	Params []*Param       `json:"params"`
+	Needs  []*CallStmt    `json:"needs"`
+	Body   []Stmt         `json:"body"`
+}
+
+// Param is a task parameter. Optional parameters take Default when no argument
+// is given, and a variadic parameter takes all remaining arguments. Values lists
+// the arguments allowed by an enum type.
+type Param struct {
+	Pos      token.Position `json:"pos"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Values   []string       `json:"values"`
+	Default  string         `json:"default"`
+	Optional bool           `json:"optional"`
+	Variadic bool           `json:"variadic"`
+}
+
+// CommandStmt is a shell command inside a task body. Script is set for
+// multi-line <<< ... >>> blocks which are handed to the shell as one script.
+// With Capture set, its trimmed standard output is stored in the variable of
+// that name instead of being printed. Timeout limits how long it may run and
+// Retry holds the attempts and backoff of @(retry) if it fails. A failure is
+// only logged when IgnoreErrors is set by a - prefix or @(ignore_errors).
+type CommandStmt struct {
+	Pos          token.Position `json:"pos"`
+	Text         string         `json:"text"`
+	Script       bool           `json:"script"`
+	Capture      string         `json:"capture"`
+	Timeout      string         `json:"timeout"`
+	Retry        []string       `json:"retry"`
+	IgnoreErrors bool           `json:"ignoreErrors"`
+}
+
+// DirectiveStmt is an @(name:"arg",...) line such as cd, os, env, eq or neq.
+type DirectiveStmt struct {
+	Pos  token.Position `json:"pos"`
+	Name string         `json:"name"`
+	Args []string       `json:"args"`
+}
+
+// CallStmt is an @task or @task("arg",...) line invoking another task.
+type CallStmt struct {
+	Pos  token.Position `json:"pos"`
+	Name string         `json:"name"`
+	Args []string       `json:"args"`
+}
+
+// IfStmt runs Then when Cond holds and Else otherwise. An else if chain is an
+// IfStmt that is the only statement of Else.
+type IfStmt struct {
+	Pos  token.Position `json:"pos"`
+	Cond *Condition     `json:"cond"`
+	Then []Stmt         `json:"then"`
+	Else []Stmt         `json:"else"`
+}
+
+type Condition struct {
+	Pos    token.Position `json:"pos"`
+	Source string         `json:"source"`
+	Expr   expr.Node      `json:"-"`
+}
+
+type Comment struct {
+	Pos  token.Position `json:"pos"`
+	Text string         `json:"text"`
+}
+
+func (f *File) Task(name string) *TaskDecl {
+	for _, task := range f.Tasks {
+		if task.Name == name {
+			return task
+		}
+	}
+
+	return nil
+}
+
+func (f *File) TaskNames() []string {
+	names := make([]string, 0, len(f.Tasks))
+	for _, task := range f.Tasks {
+		names = append(names, task.Name)
+	}
+
+	return names
+}
+
+func (t *TaskDecl) ParamNames() []string {
+	names := make([]string, 0, len(t.Params))
+	for _, param := range t.Params {
+		names = append(names, param.Name)
+	}
+
+	return names
+}
+
+// Arity returns the least and most arguments the task accepts, where most is -1
+// if the task is variadic.
+func (t *TaskDecl) Arity() (int, int) {
+	least := 0
+
+	for _, param := range t.Params {
+		if param.Variadic {
+			return least, -1
+		}
+
+		if !param.Optional {
+			least++
+		}
+	}
+
+	return least, len(t.Params)
+}
+
+// Accepts reports whether the task can be called with n arguments.
+func (t *TaskDecl) Accepts(n int) bool {
+	least, most := t.Arity()
+	return n >= least && (most == -1 || n <= most)
+}
+
+// Expects describes the number of arguments the task accepts.
+func (t *TaskDecl) Expects() string {
+	least, most := t.Arity()
+
+	switch {
+	case most == -1:
+		return "at least " + strconv.Itoa(least)
+	case least != most:
+		return strconv.Itoa(least) + " to " + strconv.Itoa(most)
+	}
+
+	return strconv.Itoa(least)
+}
+
+// Usage describes the command line arguments of the task, as in
+// build <platform:linux|darwin> [arch=amd64] [count:int] [pkgs...].
+func (t *TaskDecl) Usage() string {
+	parts := []string{t.Name}
+
+	for _, param := range t.Params {
+		name := param.Name + param.Kind()
+
+		switch {
+		case param.Variadic:
+			parts = append(parts, "["+param.Name+"..."+param.Kind()+"]")
+		case param.Optional && param.Default != "":
+			parts = append(parts, "["+name+"="+param.Default+"]")
+		case param.Optional:
+			parts = append(parts, "["+name+"]")
+		default:
+			parts = append(parts, "<"+name+">")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Param returns the parameter bound to the argument at index i, or nil if there is none.
+func (t *TaskDecl) Param(i int) *Param {
+	if n := len(t.Params); n != 0 && i >= n-1 && t.Params[n-1].Variadic {
+		return t.Params[n-1]
+	}
+
+	if i < len(t.Params) {
+		return t.Params[i]
+	}
+
+	return nil
+}
+
+// Check validates bound arguments against the types of the parameters they are bound to.
+func (t *TaskDecl) Check(bound []string) error {
+	for i, arg := range bound {
+		if param := t.Param(i); param != nil {
+			if err := param.Check(arg); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Bind fills in the defaults of optional parameters that have no argument.
+func (t *TaskDecl) Bind(args []string) []string {
+	bound := append(make([]string, 0, len(t.Params)), args...)
+
+	for _, param := range t.Params[min(len(args), len(t.Params)):] {
+		if !param.Variadic {
+			bound = append(bound, param.Default)
+		}
+	}
+
+	return bound
+}
+
+// Kind describes the type of the parameter as it appears in usage lines, as in :int
+// or :linux|darwin, or returns an empty string if the parameter is untyped.
+func (p *Param) Kind() string {
+	switch p.Type {
+	case "", TypeString:
+		return ""
+	case TypeEnum:
+		return ":" + strings.Join(p.Values, "|")
+	}
+
+	return ":" + p.Type
+}
+
+// Check validates value against the type of the parameter. The empty value of an
+// optional parameter without a default is always valid.
+func (p *Param) Check(value string) error {
+	if value == "" && p.Optional && p.Default == "" {
+		return nil
+	}
+
+	switch p.Type {
+	case TypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%w %q for %s, expects an int", ErrInvalidArgument, value, p.Name)
+		}
+	case TypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%w %q for %s, expects true or false", ErrInvalidArgument, value, p.Name)
+		}
+	case TypeEnum:
+		if !slices.Contains(p.Values, value) {
+			return fmt.Errorf("%w %q for %s, expects one of %s%s",
+				ErrInvalidArgument, value, p.Name, strings.Join(p.Values, ", "), util.Suggest(value, p.Values))
+		}
+	}
+
+	return nil
+}
+
+// Values returns the value of each parameter for bound arguments. The arguments of a
+// variadic parameter are quoted for the shell and joined with spaces, so that each one
+// stays a single argument of the command it is used in.
+func (t *TaskDecl) Values(bound []string) []string {
+	values := make([]string, 0, len(t.Params))
+
+	for i, param := range t.Params {
+		switch {
+		case param.Variadic:
+			values = append(values, util.QuoteArgs(bound[min(i, len(bound)):]))
+		case i < len(bound):
+			values = append(values, bound[i])
+		}
+	}
+
+	return values
+}
+
+func (v *VarDecl) Position() token.Position       { return v.Pos }
+func (t *TaskDecl) Position() token.Position      { return t.Pos }
+func (p *Param) Position() token.Position         { return p.Pos }
+func (c *CommandStmt) Position() token.Position   { return c.Pos }
+func (d *DirectiveStmt) Position() token.Position { return d.Pos }
+func (c *CallStmt) Position() token.Position      { return c.Pos }
+func (i *IfStmt) Position() token.Position        { return i.Pos }
+func (c *Condition) Position() token.Position     { return c.Pos }
+func (c *Comment) Position() token.Position       { return c.Pos }
+
+func (*CommandStmt) stmtNode()   {}
+func (*DirectiveStmt) stmtNode() {}
+func (*CallStmt) stmtNode()      {}
+func (*IfStmt) stmtNode()        {}
+func (*VarDecl) stmtNode()       {}
+func (*Comment) stmtNode()       {}
@@ -0,0 +1,162 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache stores the input hash of the last successful run of each task in a JSON state file.
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]string
+}
+
+func New(path string) *Cache {
+	return &Cache{mu: sync.Mutex{}, path: path, entries: nil}
+}
+
+func (c *Cache) Get(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return "", err
+	}
+
+	return c.entries[key], nil
+}
+
+func (c *Cache) Put(key, hash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	c.entries[key] = hash
+
+	data, err := json.MarshalIndent(c.entries, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil { //nolint:mnd // wontfix
+		return err
+	}
+
+	temp := c.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil { //nolint:gosec // wontfix
+		return err
+	}
+
+	return os.Rename(temp, c.path)
+}
+
+func (c *Cache) load() error {
+	if c.entries != nil {
+		return nil
+	}
+
+	c.entries = make(map[string]string)
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &c.entries)
+}
+
+// Hash digests the names and contents of files.
+func Hash(files []string) (string, error) {
+	digest := sha256.New()
+
+	for _, file := range files {
+		if err := hashFile(digest, file); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+func hashFile(digest io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.WriteString(digest, filepath.ToSlash(file)+"\x00"); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(digest, f)
+
+	return err
+}
+
+// Newer reports whether every output exists and is newer than every input.
+func Newer(outputs, inputs []string) (bool, error) {
+	oldest := time.Time{}
+
+	for _, output := range outputs {
+		info, err := os.Stat(output)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if oldest.IsZero() || info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+		}
+	}
+
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return false, err
+		}
+
+		if !info.ModTime().Before(oldest) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,81 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package diagnostic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ricochhet/gomake/token"
+)
+
+type Diagnostic struct {
+	Pos token.Position
+	Err error
+}
+
+func New(pos token.Position, err error) *Diagnostic {
+	return &Diagnostic{Pos: pos, Err: err}
+}
+
+// Wrap attaches pos to err unless err already carries a position.
+func Wrap(pos token.Position, err error) error {
+	var diag *Diagnostic
+	if errors.As(err, &diag) {
+		return err
+	}
+
+	return New(pos, err)
+}
+
+func (d *Diagnostic) Error() string {
+	return d.Pos.String() + ": " + d.Err.Error()
+}
+
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Excerpt renders the source line the diagnostic points at with a caret under the offending column.
+func (d *Diagnostic) Excerpt(text string) string {
+	lines := strings.Split(text, string(token.TokenNewLine))
+	if d.Pos.Line < 1 || d.Pos.Line > len(lines) {
+		return ""
+	}
+
+	line := strings.TrimRight(lines[d.Pos.Line-1], string(token.TokenReturn))
+	gutter := fmt.Sprintf("%5d | ", d.Pos.Line)
+
+	var caret strings.Builder
+
+	for i, r := range []rune(line) {
+		if i >= d.Pos.Column-1 {
+			break
+		}
+
+		if r == token.TokenTab {
+			caret.WriteRune(token.TokenTab)
+		} else {
+			caret.WriteRune(token.TokenSpace)
+		}
+	}
+
+	return gutter + line + "\n" + strings.Repeat(" ", len(gutter)-2) + "| " + caret.String() + "^"
+}
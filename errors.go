@@ -21,14 +21,71 @@ package main
 import (
 	"errors"
 	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/interpret"
+	"github.com/ricochhet/gomake/parser"
+	"github.com/ricochhet/gomake/process"
 )
 
 var (
-	ErrNoMakefilePath  = errors.New("no makefile path specified")
 	ErrNoFunctionName  = errors.New("no function name specified")
 	ErrInvalidFileType = errors.New("invalid file type, expects: .gomake")
 )
 
+// Exit codes. A failed command exits with the status of the command instead.
+const (
+	ExitSuccess  = 0
+	ExitFailure  = 1
+	ExitUsage    = 64
+	ExitParse    = 65
+	ExitNotFound = 66
+	ExitTimeout  = 124
+	ExitSignal   = 128
+)
+
+// ExitCode returns the exit code gomake ends with after err.
+func ExitCode(err error) int {
+	var (
+		usageErr    *interpret.UsageError
+		parseErr    *parser.Error
+		notFoundErr *interpret.NotFoundError
+		exitErr     *process.ExitError
+		signalErr   *process.SignalError
+		timeoutErr  *process.TimeoutError
+	)
+
+	switch {
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &parseErr):
+		return ExitParse
+	case errors.As(err, &notFoundErr):
+		return ExitNotFound
+	case errors.As(err, &signalErr):
+		if sig, ok := signalErr.Signal.(syscall.Signal); ok {
+			return ExitSignal + int(sig)
+		}
+	case errors.As(err, &timeoutErr):
+		return ExitTimeout
+	case errors.As(err, &exitErr) && exitErr.Code > 0:
+		return exitErr.Code
+	}
+
+	return ExitFailure
+}
+
 func Errr(err error) {
-	fmt.Println("gomake: " + err.Error())
+	fmt.Fprintln(os.Stderr, "gomake: "+err.Error())
+}
+
+func Excerpt(err error, text string) {
+	var diag *diagnostic.Diagnostic
+	if errors.As(err, &diag) {
+		if excerpt := diag.Excerpt(text); excerpt != "" {
+			fmt.Fprintln(os.Stderr, excerpt)
+		}
+	}
 }
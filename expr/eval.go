@@ -0,0 +1,329 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/token"
+	"github.com/ricochhet/gomake/util"
+)
+
+var ErrNotOrdered = errors.New("cannot order")
+
+// Env resolves what an expression refers to when it is evaluated.
+type Env struct {
+	// Expand substitutes parameters and variables in quoted strings.
+	Expand func(text string) (string, error)
+	// Getenv looks up an environment variable.
+	Getenv func(key string) (string, bool)
+	// Dir is the directory relative paths are resolved against.
+	Dir string
+}
+
+// Function is a function callable from expressions. Check, if set, validates
+// the arguments when the expression is parsed.
+type Function struct {
+	Args  int
+	Check func(args []Node) error
+	Call  func(env *Env, args []any) (any, error)
+}
+
+//nolint:gochecknoglobals // wontfix
+var Variables = map[string]string{
+	"os":       runtime.GOOS,
+	"arch":     runtime.GOARCH,
+	"platform": runtime.GOOS + "/" + runtime.GOARCH,
+}
+
+//nolint:gochecknoglobals // wontfix
+var Functions = map[string]Function{
+	"eq": {Args: 2, Check: nil, Call: func(_ *Env, args []any) (any, error) {
+		return equal(args[0], args[1]), nil
+	}},
+	"neq": {Args: 2, Check: nil, Call: func(_ *Env, args []any) (any, error) {
+		return !equal(args[0], args[1]), nil
+	}},
+	"os": {Args: 1, Check: checkLiteral(util.CheckOS), Call: func(_ *Env, args []any) (any, error) {
+		return String(args[0]) == runtime.GOOS || String(args[0]) == "all", nil
+	}},
+	"arch": {Args: 1, Check: checkLiteral(util.CheckArch), Call: func(_ *Env, args []any) (any, error) {
+		return String(args[0]) == runtime.GOARCH || String(args[0]) == "all", nil
+	}},
+	"platform": {Args: 1, Check: checkLiteral(util.CheckPlatform), Call: func(_ *Env, args []any) (any, error) {
+		return String(args[0]) == Variables["platform"] || String(args[0]) == "all", nil
+	}},
+	"defined": {Args: 1, Check: checkLiteral(util.CheckVariableName), Call: func(env *Env, args []any) (any, error) {
+		_, ok := env.Getenv(String(args[0]))
+		return ok, nil
+	}},
+	"env": {Args: 1, Check: nil, Call: func(env *Env, args []any) (any, error) {
+		value, _ := env.Getenv(String(args[0]))
+		return value, nil
+	}},
+	"semver": {Args: 1, Check: nil, Call: func(_ *Env, args []any) (any, error) {
+		return ParseVersion(String(args[0]))
+	}},
+	"exists":   {Args: 1, Check: nil, Call: exists},
+	"is_dir":   {Args: 1, Check: nil, Call: isDir},
+	"is_file":  {Args: 1, Check: nil, Call: isFile},
+	"newer":    {Args: 2, Check: nil, Call: newer},
+	"glob_any": {Args: 1, Check: nil, Call: globAny},
+}
+
+// Test evaluates node and reports whether the result is true.
+func Test(node Node, env *Env) (bool, error) {
+	value, err := Eval(node, env)
+	if err != nil {
+		return false, err
+	}
+
+	return Truth(value), nil
+}
+
+// Eval evaluates node to a string, a bool or a Version.
+func Eval(node Node, env *Env) (any, error) {
+	switch node := node.(type) {
+	case *Literal:
+		if !node.Quoted {
+			return node.Value, nil
+		}
+
+		return env.Expand(node.Value)
+	case *Ident:
+		return Variables[node.Name], nil
+	case *Call:
+		args := make([]any, 0, len(node.Args))
+
+		for _, arg := range node.Args {
+			value, err := Eval(arg, env)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, value)
+		}
+
+		value, err := Functions[node.Name].Call(env, args)
+		if err != nil {
+			return nil, diagnostic.Wrap(node.Pos, err)
+		}
+
+		return value, nil
+	case *Unary:
+		ok, err := Test(node.X, env)
+		return !ok, err
+	case *Binary:
+		return evalBinary(node, env)
+	case *In:
+		return evalIn(node, env)
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrSyntax, node)
+}
+
+func evalBinary(node *Binary, env *Env) (any, error) {
+	x, err := Eval(node.X, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.Op {
+	case "&&":
+		if !Truth(x) {
+			return false, nil
+		}
+
+		return Test(node.Y, env)
+	case "||":
+		if Truth(x) {
+			return true, nil
+		}
+
+		return Test(node.Y, env)
+	}
+
+	y, err := Eval(node.Y, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.Op {
+	case "==":
+		return equal(x, y), nil
+	case "!=":
+		return !equal(x, y), nil
+	case "=~":
+		re, err := regexp.Compile(String(y))
+		if err != nil {
+			return nil, diagnostic.New(node.Y.Position(), err)
+		}
+
+		return re.MatchString(String(x)), nil
+	}
+
+	order, err := compare(x, y)
+	if err != nil {
+		return nil, diagnostic.New(node.Pos, err)
+	}
+
+	switch node.Op {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	}
+
+	return order >= 0, nil
+}
+
+func evalIn(node *In, env *Env) (any, error) {
+	x, err := Eval(node.X, env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range node.List {
+		y, err := Eval(item, env)
+		if err != nil {
+			return nil, err
+		}
+
+		if equal(x, y) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Truth reports whether a value counts as true: strings are true unless empty or read as
+// false by strconv.ParseBool, such as "false" and "0".
+func Truth(value any) bool {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case string:
+		if value == "" {
+			return false
+		}
+
+		b, err := strconv.ParseBool(value)
+
+		return err != nil || b
+	}
+
+	return value != nil
+}
+
+func String(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case Version:
+		return value.Text
+	}
+
+	return fmt.Sprint(value)
+}
+
+// equal compares versions semantically if either side is one, and strings otherwise.
+func equal(x, y any) bool {
+	if _, ok := x.(Version); ok {
+		order, err := compare(x, y)
+		return err == nil && order == 0
+	}
+
+	if _, ok := y.(Version); ok {
+		order, err := compare(x, y)
+		return err == nil && order == 0
+	}
+
+	return String(x) == String(y)
+}
+
+// compare orders versions if either side is one, and numbers otherwise.
+func compare(x, y any) (int, error) {
+	_, xv := x.(Version)
+	_, yv := y.(Version)
+
+	if xv || yv {
+		a, err := toVersion(x)
+		if err != nil {
+			return 0, err
+		}
+
+		b, err := toVersion(y)
+		if err != nil {
+			return 0, err
+		}
+
+		return a.Compare(b), nil
+	}
+
+	a, aErr := strconv.ParseFloat(String(x), 64)
+	b, bErr := strconv.ParseFloat(String(y), 64)
+
+	if aErr != nil || bErr != nil {
+		return 0, fmt.Errorf("%w %q and %q, expects numbers or semver(...)", ErrNotOrdered, String(x), String(y))
+	}
+
+	switch {
+	case a < b:
+		return -1, nil
+	case a > b:
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+func toVersion(value any) (Version, error) {
+	if version, ok := value.(Version); ok {
+		return version, nil
+	}
+
+	return ParseVersion(String(value))
+}
+
+// checkLiteral validates a literal argument, unless it refers to a parameter.
+func checkLiteral(check func(string) error) func(args []Node) error {
+	return func(args []Node) error {
+		literal, ok := args[0].(*Literal)
+		if !ok || strings.ContainsRune(literal.Value, token.TokenLeftBracket) {
+			return nil
+		}
+
+		if err := check(literal.Value); err != nil {
+			return diagnostic.New(literal.Pos, err)
+		}
+
+		return nil
+	}
+}
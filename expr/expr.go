@@ -0,0 +1,364 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package expr implements the boolean expressions used by if blocks and @(when).
+//
+//	expr    = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = primary [ ( "==" | "!=" | "=~" | "<" | "<=" | ">" | ">=" ) primary | "in" list ]
+//	primary = string | number | name | name "(" [ expr { "," expr } ] ")" | "(" expr ")"
+//	list    = "[" [ primary { "," primary } ] "]"
+//
+// Strings are quoted with either " or ' and cannot contain their own quote.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/token"
+)
+
+var (
+	ErrSyntax          = errors.New("invalid expression")
+	ErrUnknownFunction = errors.New("unknown function")
+	ErrUnknownVariable = errors.New("unknown variable")
+	ErrArgumentCount   = errors.New("wrong number of arguments")
+)
+
+type Node interface {
+	Position() token.Position
+}
+
+// Literal is a quoted string, or a bare number or version. Quoted strings are
+// expanded when the expression is evaluated.
+type Literal struct {
+	Pos    token.Position
+	Value  string
+	Quoted bool
+}
+
+type Ident struct {
+	Pos  token.Position
+	Name string
+}
+
+type Call struct {
+	Pos  token.Position
+	Name string
+	Args []Node
+}
+
+type Unary struct {
+	Pos token.Position
+	Op  string
+	X   Node
+}
+
+type Binary struct {
+	Pos token.Position
+	Op  string
+	X   Node
+	Y   Node
+}
+
+type In struct {
+	Pos  token.Position
+	X    Node
+	List []Node
+}
+
+func (l *Literal) Position() token.Position { return l.Pos }
+func (i *Ident) Position() token.Position   { return i.Pos }
+func (c *Call) Position() token.Position    { return c.Pos }
+func (u *Unary) Position() token.Position   { return u.Pos }
+func (b *Binary) Position() token.Position  { return b.Pos }
+func (i *In) Position() token.Position      { return i.Pos }
+
+type parser struct {
+	text  string
+	pos   token.Position
+	items []item
+	index int
+}
+
+// Parse parses text, which starts at pos in the file it was read from.
+func Parse(text string, pos token.Position) (Node, error) {
+	items, offset, err := lex(text)
+	if err != nil {
+		return nil, diagnostic.New(at(pos, text, offset), err)
+	}
+
+	p := &parser{text: text, pos: pos, items: items, index: 0}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if it := p.peek(); it.kind != itemEOF {
+		return nil, p.unexpected(it)
+	}
+
+	return node, nil
+}
+
+func at(pos token.Position, text string, offset int) token.Position {
+	pos.Offset += offset
+	pos.Column += utf8.RuneCountInString(text[:offset])
+
+	return pos
+}
+
+func (p *parser) at(it item) token.Position {
+	return at(p.pos, p.text, it.offset)
+}
+
+func (p *parser) peek() item {
+	return p.items[p.index]
+}
+
+func (p *parser) next() item {
+	it := p.items[p.index]
+	if it.kind != itemEOF {
+		p.index++
+	}
+
+	return it
+}
+
+func (p *parser) accept(operator string) (item, bool) {
+	if it := p.peek(); it.kind == itemOperator && it.text == operator {
+		return p.next(), true
+	}
+
+	return item{kind: itemEOF, text: "", offset: 0}, false
+}
+
+func (p *parser) expect(operator string) error {
+	if _, ok := p.accept(operator); !ok {
+		return p.unexpected(p.peek())
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected(it item) error {
+	if it.kind == itemEOF {
+		return diagnostic.New(p.at(it), fmt.Errorf("%w: unexpected end", ErrSyntax))
+	}
+
+	return diagnostic.New(p.at(it), fmt.Errorf("%w: unexpected %q", ErrSyntax, it.text))
+}
+
+func (p *parser) parseOr() (Node, error) {
+	return p.parseBinary("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	return p.parseBinary("&&", p.parseUnary)
+}
+
+func (p *parser) parseBinary(operator string, operand func() (Node, error)) (Node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept(operator)
+		if !ok {
+			return x, nil
+		}
+
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+
+		x = &Binary{Pos: p.at(op), Op: operator, X: x, Y: y}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	op, ok := p.accept("!")
+	if !ok {
+		return p.parseCompare()
+	}
+
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Unary{Pos: p.at(op), Op: "!", X: x}, nil
+}
+
+func (p *parser) parseCompare() (Node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+
+	switch {
+	case op.kind == itemIdent && op.text == "in":
+		p.next()
+
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+
+		return &In{Pos: p.at(op), X: x, List: list}, nil
+	case op.kind == itemOperator && comparisons[op.text]:
+		p.next()
+
+		y, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkPattern(op.text, y); err != nil {
+			return nil, err
+		}
+
+		return &Binary{Pos: p.at(op), Op: op.text, X: x, Y: y}, nil
+	}
+
+	return x, nil
+}
+
+// checkPattern reports invalid regular expressions that are known before evaluation.
+func checkPattern(operator string, y Node) error {
+	literal, ok := y.(*Literal)
+	if operator != "=~" || !ok || strings.ContainsRune(literal.Value, token.TokenLeftBracket) {
+		return nil
+	}
+
+	if _, err := regexp.Compile(literal.Value); err != nil {
+		return diagnostic.New(literal.Pos, err)
+	}
+
+	return nil
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	it := p.next()
+
+	switch it.kind {
+	case itemString, itemNumber:
+		return &Literal{Pos: p.at(it), Value: it.text, Quoted: it.kind == itemString}, nil
+	case itemIdent:
+		if p.peek().kind == itemOperator && p.peek().text == "(" {
+			return p.parseCall(it)
+		}
+
+		if _, ok := Variables[it.text]; !ok {
+			return nil, diagnostic.New(p.at(it), fmt.Errorf("%w %q", ErrUnknownVariable, it.text))
+		}
+
+		return &Ident{Pos: p.at(it), Name: it.text}, nil
+	case itemOperator:
+		if it.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			return x, p.expect(")")
+		}
+	case itemEOF:
+	}
+
+	return nil, p.unexpected(it)
+}
+
+func (p *parser) parseCall(name item) (Node, error) {
+	fn, ok := Functions[name.text]
+	if !ok {
+		return nil, diagnostic.New(p.at(name), fmt.Errorf("%w %q", ErrUnknownFunction, name.text))
+	}
+
+	p.next()
+
+	call := &Call{Pos: p.at(name), Name: name.text, Args: []Node{}}
+
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			call.Args = append(call.Args, arg)
+
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(call.Args) != fn.Args {
+		return nil, diagnostic.New(call.Pos,
+			fmt.Errorf("%w in call to %s: expects %d, got %d", ErrArgumentCount, name.text, fn.Args, len(call.Args)))
+	}
+
+	if fn.Check != nil {
+		if err := fn.Check(call.Args); err != nil {
+			return nil, diagnostic.Wrap(call.Pos, err)
+		}
+	}
+
+	return call, nil
+}
+
+func (p *parser) parseList() ([]Node, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+
+	list := make([]Node, 0)
+
+	if _, ok := p.accept("]"); ok {
+		return list, nil
+	}
+
+	for {
+		x, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, x)
+
+		if _, ok := p.accept(","); !ok {
+			return list, p.expect("]")
+		}
+	}
+}
@@ -0,0 +1,292 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package expr_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"testing"
+	"time"
+
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/expr"
+	"github.com/ricochhet/gomake/token"
+)
+
+func testEnv() *expr.Env {
+	vars := map[string]string{"HOME": "/home/gomake"}
+
+	return &expr.Env{
+		Expand: func(text string) (string, error) { return text, nil },
+		Getenv: func(key string) (string, bool) {
+			value, ok := vars[key]
+			return value, ok
+		},
+		Dir: ".",
+	}
+}
+
+func TestEval(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`!"" && "x"`, true},
+		{`!("x" && "")`, true},
+		{`!"x" || "x"`, true},
+		{`"" || "x" && ""`, false},
+		{`"x" || "" && ""`, true},
+		{`("x" || "") && ""`, false},
+		{`!!"x"`, true},
+		{`"false"`, false},
+		{`"0"`, false},
+		{`"no"`, true},
+		{`"b" in ["a", "b"]`, true},
+		{`"c" in ["a", "b"]`, false},
+		{`"a" in []`, false},
+		{`!("c" in ["a"])`, true},
+		{`"v1.22.3" =~ "^v1\.2[0-9]"`, true},
+		{`"v2.0.0" =~ "^v1"`, false},
+		{`2 < 10`, true},
+		{`"2" < "10"`, true},
+		{`1.5 >= 1.50`, true},
+		{`3 <= 2`, false},
+		{`semver("1.10.0") > semver("1.9.9")`, true},
+		{`semver("v1.2") == semver("1.2.0")`, true},
+		{`semver("1.0.0-rc.1") < "1.0.0"`, true},
+		{`semver("1.0.0") >= "1.0.0-rc.1"`, true},
+		{`defined("HOME") && !defined("GOMAKE_UNSET")`, true},
+		{`env("HOME") == "/home/gomake"`, true},
+		{`eq("a", "a") && neq("a", "b")`, true},
+	}
+
+	for _, test := range tests {
+		node, err := expr.Parse(test.text, token.Position{Filename: "test", Offset: 0, Line: 1, Column: 1})
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.text, err)
+			continue
+		}
+
+		got, err := expr.Test(node, testEnv())
+		if err != nil {
+			t.Errorf("Test(%q): %v", test.text, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("Test(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		err    error
+		column int
+	}{
+		{`"a" ==`, expr.ErrSyntax, 7},
+		{`"a" && && "b"`, expr.ErrSyntax, 8},
+		{`("a"`, expr.ErrSyntax, 5},
+		{`"a" "b"`, expr.ErrSyntax, 5},
+		{`"a" # "b"`, expr.ErrSyntax, 5},
+		{`"a`, nil, 1},
+		{`nope == "a"`, expr.ErrUnknownVariable, 1},
+		{`"a" == nope("a")`, expr.ErrUnknownFunction, 8},
+		{`eq("a")`, expr.ErrArgumentCount, 1},
+		{`"a" in "b"`, expr.ErrSyntax, 8},
+		{`"a" =~ "("`, nil, 8},
+	}
+
+	for _, test := range tests {
+		_, err := expr.Parse(test.text, token.Position{Filename: "test", Offset: 0, Line: 3, Column: 1})
+		if err == nil {
+			t.Errorf("Parse(%q): expected an error", test.text)
+			continue
+		}
+
+		if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("Parse(%q) = %v, want %v", test.text, err, test.err)
+		}
+
+		var diag *diagnostic.Diagnostic
+		if !errors.As(err, &diag) {
+			t.Errorf("Parse(%q) = %v, want a position", test.text, err)
+			continue
+		}
+
+		if diag.Pos.Line != 3 || diag.Pos.Column != test.column {
+			t.Errorf("Parse(%q) at %d:%d, want 3:%d", test.text, diag.Pos.Line, diag.Pos.Column, test.column)
+		}
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		err    error
+		column int
+	}{
+		{`"a" < "b"`, expr.ErrNotOrdered, 5},
+		{`semver("x.y") < "1.0.0"`, expr.ErrInvalidVersion, 1},
+		{`"a" =~ "{pattern}"`, nil, 8},
+	}
+
+	env := testEnv()
+	env.Expand = func(text string) (string, error) {
+		if text == "{pattern}" {
+			return "[", nil
+		}
+
+		return text, nil
+	}
+
+	for _, test := range tests {
+		node, err := expr.Parse(test.text, token.Position{Filename: "test", Offset: 0, Line: 1, Column: 1})
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.text, err)
+			continue
+		}
+
+		_, err = expr.Eval(node, env)
+		if err == nil {
+			t.Errorf("Eval(%q): expected an error", test.text)
+			continue
+		}
+
+		if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("Eval(%q) = %v, want %v", test.text, err, test.err)
+		}
+
+		var diag *diagnostic.Diagnostic
+		if errors.As(err, &diag) && diag.Pos.Column != test.column {
+			t.Errorf("Eval(%q) at column %d, want %d", test.text, diag.Pos.Column, test.column)
+		}
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	_, err := expr.Parse(`"a" =~ "("`, token.Position{Filename: "test", Offset: 0, Line: 1, Column: 1})
+
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Parse = %v, want a regexp syntax error", err)
+	}
+}
+
+func TestFilePredicates(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for _, name := range []string{"old.txt", "new.txt", "dist/a.tar.gz"} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old.txt"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{`exists("old.txt")`, true},
+		{`exists("dist")`, true},
+		{`exists("missing")`, false},
+		{`is_dir("dist")`, true},
+		{`is_dir("old.txt")`, false},
+		{`is_dir("missing")`, false},
+		{`is_file("old.txt")`, true},
+		{`is_file("dist")`, false},
+		{`is_file("missing")`, false},
+		{`newer("new.txt", "old.txt")`, true},
+		{`newer("old.txt", "new.txt")`, false},
+		{`newer("old.txt", "missing")`, true},
+		{`newer("missing", "old.txt")`, false},
+		{`glob_any("dist/*.tar.gz")`, true},
+		{`glob_any("**/*.tar.gz")`, true},
+		{`glob_any("*.zip")`, false},
+		{`glob_any("dist")`, false},
+		{`exists("` + filepath.ToSlash(filepath.Join(dir, "new.txt")) + `")`, true},
+	}
+
+	env := testEnv()
+	env.Dir = dir
+
+	for _, test := range tests {
+		node, err := expr.Parse(test.text, token.Position{Filename: "test", Offset: 0, Line: 1, Column: 1})
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.text, err)
+			continue
+		}
+
+		got, err := expr.Test(node, env)
+		if err != nil {
+			t.Errorf("Test(%q): %v", test.text, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("Test(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	env := testEnv()
+
+	env.Dir = dir
+	if got := env.Path("sub"); got != filepath.Join(dir, "sub") {
+		t.Errorf("Path(%q) = %q, want it resolved against %q", "sub", got, dir)
+	}
+
+	if got := env.Path(filepath.Join(dir, "abs")); got != filepath.Join(dir, "abs") {
+		t.Errorf("Path = %q, want absolute paths unchanged", got)
+	}
+
+	env.Dir = ""
+	if got := env.Path("sub"); got != "sub" {
+		t.Errorf("Path(%q) = %q without a directory, want it unchanged", "sub", got)
+	}
+}
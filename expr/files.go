@@ -0,0 +1,88 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package expr
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ricochhet/gomake/util"
+)
+
+// Path resolves a relative path against the directory of the environment.
+func (e *Env) Path(name string) string {
+	if e.Dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(e.Dir, name)
+}
+
+func exists(env *Env, args []any) (any, error) {
+	_, err := stat(env, args[0])
+	return err == nil, ignoreNotExist(err)
+}
+
+func isDir(env *Env, args []any) (any, error) {
+	info, err := stat(env, args[0])
+	return err == nil && info.IsDir(), ignoreNotExist(err)
+}
+
+func isFile(env *Env, args []any) (any, error) {
+	info, err := stat(env, args[0])
+	return err == nil && info.Mode().IsRegular(), ignoreNotExist(err)
+}
+
+// newer reports whether the first path was modified after the second, or the second does not exist.
+func newer(env *Env, args []any) (any, error) {
+	a, err := stat(env, args[0])
+	if err != nil {
+		return false, ignoreNotExist(err)
+	}
+
+	b, err := stat(env, args[1])
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist), ignoreNotExist(err)
+	}
+
+	return a.ModTime().After(b.ModTime()), nil
+}
+
+func globAny(env *Env, args []any) (any, error) {
+	matches, err := util.Glob(env.Path(String(args[0])))
+	if err != nil {
+		return false, err
+	}
+
+	return len(matches) != 0, nil
+}
+
+func stat(env *Env, name any) (fs.FileInfo, error) {
+	return os.Stat(env.Path(String(name)))
+}
+
+func ignoreNotExist(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
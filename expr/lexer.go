@@ -0,0 +1,114 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errUnterminatedString = errors.New("unterminated string")
+
+type itemKind int
+
+const (
+	itemEOF itemKind = iota
+	itemIdent
+	itemNumber
+	itemString
+	itemOperator
+)
+
+type item struct {
+	kind   itemKind
+	text   string
+	offset int
+}
+
+//nolint:gochecknoglobals // wontfix
+var (
+	// Longer operators come first so that "<=" is not read as "<".
+	operators   = []string{"&&", "||", "==", "!=", "=~", "<=", ">=", "!", "<", ">", "(", ")", "[", "]", ","}
+	comparisons = map[string]bool{"==": true, "!=": true, "=~": true, "<": true, "<=": true, ">": true, ">=": true}
+)
+
+// lex splits text into items. On failure it returns the offset of the offending character.
+func lex(text string) ([]item, int, error) {
+	items := make([]item, 0)
+
+	for i := 0; i < len(text); {
+		c := text[i]
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(text[i+1:], c)
+			if end == -1 {
+				return nil, i, errUnterminatedString
+			}
+
+			items = append(items, item{kind: itemString, text: text[i+1 : i+1+end], offset: i})
+			i += end + 2 //nolint:mnd // wontfix
+		case isDigit(c):
+			end := scan(text, i, func(c byte) bool { return isLetter(c) || isDigit(c) || strings.IndexByte(".-+", c) != -1 })
+			items = append(items, item{kind: itemNumber, text: text[i:end], offset: i})
+			i = end
+		case isLetter(c):
+			end := scan(text, i, func(c byte) bool { return isLetter(c) || isDigit(c) })
+			items = append(items, item{kind: itemIdent, text: text[i:end], offset: i})
+			i = end
+		default:
+			operator := ""
+
+			for _, op := range operators {
+				if strings.HasPrefix(text[i:], op) {
+					operator = op
+					break
+				}
+			}
+
+			if operator == "" {
+				return nil, i, fmt.Errorf("%w: unexpected %q", ErrSyntax, text[i])
+			}
+
+			items = append(items, item{kind: itemOperator, text: operator, offset: i})
+			i += len(operator)
+		}
+	}
+
+	return append(items, item{kind: itemEOF, text: "", offset: len(text)}), 0, nil
+}
+
+func scan(text string, i int, predicate func(byte) bool) int {
+	for i < len(text) && predicate(text[i]) {
+		i++
+	}
+
+	return i
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,117 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package expr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidVersion = errors.New("invalid semantic version")
+
+// Version is a semantic version. Missing minor and patch numbers count as zero
+// and a leading v is ignored.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease []string
+	Text       string
+}
+
+func ParseVersion(text string) (Version, error) {
+	version := Version{Major: 0, Minor: 0, Patch: 0, Prerelease: []string{}, Text: text}
+
+	core, _, _ := strings.Cut(strings.TrimPrefix(text, "v"), "+")
+	core, prerelease, found := strings.Cut(core, "-")
+
+	if found {
+		version.Prerelease = strings.Split(prerelease, ".")
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 { //nolint:mnd // wontfix
+		return version, fmt.Errorf("%w %q", ErrInvalidVersion, text)
+	}
+
+	numbers := []*int{&version.Major, &version.Minor, &version.Patch}
+
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, fmt.Errorf("%w %q", ErrInvalidVersion, text)
+		}
+
+		*numbers[i] = n
+	}
+
+	return version, nil
+}
+
+// Compare orders versions by precedence: a prerelease comes before its release.
+func (v Version) Compare(other Version) int {
+	if c := cmp.Compare(v.Major, other.Major); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	switch {
+	case len(v.Prerelease) == 0 && len(other.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(other.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		if c := compareIdentifier(v.Prerelease[i], other.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(v.Prerelease), len(other.Prerelease))
+}
+
+// compareIdentifier orders numeric identifiers numerically and before alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	x, xErr := strconv.Atoi(a)
+	y, yErr := strconv.Atoi(b)
+
+	switch {
+	case xErr == nil && yErr == nil:
+		return cmp.Compare(x, y)
+	case xErr == nil:
+		return -1
+	case yErr == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,113 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package expr_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ricochhet/gomake/expr"
+)
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text                string
+		major, minor, patch int
+		prerelease          int
+		err                 error
+	}{
+		{"1.2.3", 1, 2, 3, 0, nil},
+		{"v1.2.3", 1, 2, 3, 0, nil},
+		{"1.2", 1, 2, 0, 0, nil},
+		{"1", 1, 0, 0, 0, nil},
+		{"1.2.3-rc.1", 1, 2, 3, 2, nil},
+		{"1.2.3-rc.1+build.5", 1, 2, 3, 2, nil},
+		{"1.2.3+build", 1, 2, 3, 0, nil},
+		{"1.2.3.4", 0, 0, 0, 0, expr.ErrInvalidVersion},
+		{"1.x", 0, 0, 0, 0, expr.ErrInvalidVersion},
+		{"1.-2", 0, 0, 0, 0, expr.ErrInvalidVersion},
+		{"", 0, 0, 0, 0, expr.ErrInvalidVersion},
+	}
+
+	for _, test := range tests {
+		version, err := expr.ParseVersion(test.text)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("ParseVersion(%q) = %v, want %v", test.text, err, test.err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseVersion(%q): %v", test.text, err)
+			continue
+		}
+
+		got := []int{version.Major, version.Minor, version.Patch, len(version.Prerelease)}
+		if !slices.Equal(got, []int{test.major, test.minor, test.patch, test.prerelease}) {
+			t.Errorf("ParseVersion(%q) = %+v", test.text, version)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2", "1.2.0", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+	}
+
+	for _, test := range tests {
+		a, err := expr.ParseVersion(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := expr.ParseVersion(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := a.Compare(b); got != test.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+
+		if got := b.Compare(a); got != -test.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", test.b, test.a, got, -test.want)
+		}
+	}
+}
@@ -21,10 +21,12 @@ package main
 import (
 	"errors"
 	"flag"
-	"strings"
+	"fmt"
+	"os"
+	"runtime"
 
 	aflag "github.com/ricochhet/gomake/flag"
-	"github.com/ricochhet/gomake/util"
+	"github.com/ricochhet/gomake/process"
 )
 
 var ErrTooFewArguments = errors.New("too few arguments for execution")
@@ -37,6 +39,13 @@ var (
 		Arguments: []string{},
 		Extension: ".gomake",
 		Dump:      false,
+		DryRun:    false,
+		Jobs:      runtime.GOMAXPROCS(0),
+		KeepGoing: false,
+		Grace:     process.DefaultGrace,
+		Timeout:   0,
+		List:      false,
+		JSON:      false,
 	}
 )
 
@@ -47,12 +56,38 @@ func Newflag() *aflag.Flags {
 //nolint:gochecknoinits // wontfix
 func init() {
 	flag.BoolVar(&flags.Dump, "dump", false, "dump parsed function block to console")
-	flag.StringVar(&flags.Function, "run", "", "specify the task run")
+	flag.BoolVar(&flags.DryRun, "n", false, "print the commands that would run without running them")
+	flag.BoolVar(&flags.DryRun, "dry-run", false, "print the commands that would run without running them")
+	flag.StringVar(&flags.Function, "run", "", "specify the task to run, instead of the first argument")
 	flag.StringVar(&flags.Path, "path", "", "specify the gomake file to use")
-	args := flag.String("args", "", "specify the arguments to pass into the function block")
-	flag.Parse()
+	flag.IntVar(&flags.Jobs, "j", flags.Jobs, "specify the number of tasks to run in parallel")
+	flag.BoolVar(&flags.KeepGoing, "k", false, "keep running independent tasks after a task fails")
+	flag.BoolVar(&flags.KeepGoing, "keep-going", false, "keep running independent tasks after a task fails")
+	flag.DurationVar(&flags.Grace, "grace", flags.Grace, "specify how long interrupted commands have to exit before they are killed")
+	flag.DurationVar(&flags.Timeout, "timeout", 0, "specify how long the whole run may take, 0 for no limit")
+	flag.BoolVar(&flags.List, "list", false, "list the tasks of the gomake file with their parameters and descriptions")
+	flag.BoolVar(&flags.JSON, "json", false, "print the task list as JSON")
+	flag.Usage = usage
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
 
-	if *args != "" {
-		flags.Arguments = append(flags.Arguments, strings.Split(*args, util.Separator())...)
+	if err := flag.CommandLine.Parse(os.Args[1:]); errors.Is(err, flag.ErrHelp) {
+		os.Exit(ExitSuccess)
+	} else if err != nil {
+		os.Exit(ExitUsage)
 	}
+
+	function, args, err := aflag.Task(flag.CommandLine, os.Args[1:], flags.Function)
+	if err != nil {
+		Errr(err)
+		flag.Usage()
+		os.Exit(ExitUsage)
+	}
+
+	flags.Function = function
+	flags.Arguments = append(flags.Arguments, args...)
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: gomake [flags] task [arg ...] [name=value ...]\n\n")
+	flag.PrintDefaults()
 }
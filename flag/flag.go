@@ -18,10 +18,19 @@
 
 package flag
 
+import "time"
+
 type Flags struct {
 	Path      string
 	Function  string
 	Arguments []string
 	Extension string
 	Dump      bool
+	DryRun    bool
+	Jobs      int
+	KeepGoing bool
+	Grace     time.Duration
+	Timeout   time.Duration
+	List      bool
+	JSON      bool
 }
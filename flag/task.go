@@ -0,0 +1,64 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package flag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var ErrFlagAfterTask = errors.New("flags must come before the task name, use -- to pass it as an argument")
+
+// Task splits the arguments of a command line into the task to run and its arguments.
+// args are the arguments given to fs.Parse, and function is the task set by a flag, if any.
+// An argument naming a flag of fs after the task is rejected, since it would otherwise be
+// passed to the task, unless it follows --. fs.Parse drops a -- that ends the flags, so it
+// is looked up in args.
+func Task(fs *flag.FlagSet, args []string, function string) (string, []string, error) {
+	rest := fs.Args()
+	terminated := len(rest) < len(args) && args[len(args)-len(rest)-1] == "--"
+
+	if function == "" && len(rest) != 0 {
+		function, rest = rest[0], rest[1:]
+	}
+
+	if terminated {
+		return function, rest, nil
+	}
+
+	result := make([]string, 0, len(rest))
+
+	for i, arg := range rest {
+		if arg == "--" {
+			return function, append(result, rest[i+1:]...), nil
+		}
+
+		if strings.HasPrefix(arg, "-") {
+			if name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "="); fs.Lookup(name) != nil {
+				return "", nil, fmt.Errorf("%w: %s", ErrFlagAfterTask, arg)
+			}
+		}
+
+		result = append(result, arg)
+	}
+
+	return function, result, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package flag_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"slices"
+	"testing"
+
+	aflag "github.com/ricochhet/gomake/flag"
+)
+
+func TestTask(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args []string
+		task string
+		want []string
+		err  error
+	}{
+		{[]string{"build"}, "build", []string{}, nil},
+		{[]string{"-k", "build", "linux", "jobs=2"}, "build", []string{"linux", "jobs=2"}, nil},
+		{[]string{"build", "-k"}, "", nil, aflag.ErrFlagAfterTask},
+		{[]string{"build", "--run=x"}, "", nil, aflag.ErrFlagAfterTask},
+		{[]string{"build", "-x"}, "build", []string{"-x"}, nil},
+		{[]string{"build", "--", "-k"}, "build", []string{"-k"}, nil},
+		{[]string{"-k", "--", "build", "-k"}, "build", []string{"-k"}, nil},
+		{[]string{"-run", "build", "-k"}, "build", []string{}, nil},
+		{[]string{"-run", "build", "--", "-k"}, "build", []string{"-k"}, nil},
+		{[]string{"-run", "build", "--", "--", "-k"}, "build", []string{"--", "-k"}, nil},
+		{[]string{"-run", "build", "linux", "--", "-k"}, "build", []string{"linux", "-k"}, nil},
+		{[]string{"-run", "build", "-k", "linux"}, "build", []string{"linux"}, nil},
+	}
+
+	for _, test := range tests {
+		fs := flag.NewFlagSet("gomake", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Bool("k", false, "")
+		function := fs.String("run", "", "")
+
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatalf("Parse(%q): %v", test.args, err)
+		}
+
+		task, args, err := aflag.Task(fs, test.args, *function)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Task(%q) = %v, want %v", test.args, err, test.err)
+			continue
+		}
+
+		if task != test.task || !slices.Equal(args, test.want) {
+			t.Errorf("Task(%q) = %q, %q, want %q, %q", test.args, task, args, test.task, test.want)
+		}
+	}
+}
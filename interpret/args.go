@@ -0,0 +1,143 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/object"
+	"github.com/ricochhet/gomake/util"
+)
+
+var (
+	ErrUnknownParameter  = errors.New("unknown parameter")
+	ErrDuplicateArgument = errors.New("argument given twice for parameter")
+	ErrTooManyArguments  = errors.New("too many arguments")
+)
+
+// UsageError is a command line that does not match the parameters of a task.
+type UsageError struct {
+	Err   error
+	Usage string
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error() + "\nusage: gomake " + e.Usage
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError is a task that is not declared in the file.
+type NotFoundError struct {
+	Name       string
+	Suggestion string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: %q%s", object.ErrBlockNotFound, e.Name, e.Suggestion)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return object.ErrBlockNotFound
+}
+
+// BindArgs matches command line arguments to the parameters of task. Arguments of the form
+// name=value set the parameter of that name, the others fill the remaining parameters in
+// order. The result has one argument per parameter, followed by the variadic arguments.
+func BindArgs(task *ast.TaskDecl, args []string) ([]string, error) {
+	values := make([]*string, len(task.Params))
+	positional := make([]string, 0, len(args))
+	rest := make([]string, 0)
+
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || util.CheckVariableName(name) != nil {
+			positional = append(positional, arg)
+			continue
+		}
+
+		i := slices.IndexFunc(task.Params, func(param *ast.Param) bool { return param.Name == name })
+
+		switch {
+		case i == -1 && isVariadic(task):
+			positional = append(positional, arg)
+		case i == -1:
+			return nil, usage(task, fmt.Errorf("%w %q for task %s", ErrUnknownParameter, name, task.Name))
+		case task.Params[i].Variadic:
+			// Keeps its place among the positional arguments that also end up variadic.
+			positional = append(positional, value)
+		case values[i] != nil:
+			return nil, usage(task, fmt.Errorf("%w %q", ErrDuplicateArgument, name))
+		default:
+			values[i] = &value
+		}
+	}
+
+	next := 0
+
+	for _, arg := range positional {
+		for next < len(task.Params) && !task.Params[next].Variadic && values[next] != nil {
+			next++
+		}
+
+		switch {
+		case next == len(task.Params):
+			return nil, usage(task, fmt.Errorf("%w: %s expects %s, got %d", ErrTooManyArguments, task.Name, task.Expects(), len(args)))
+		case task.Params[next].Variadic:
+			rest = append(rest, arg)
+		default:
+			values[next] = &arg
+		}
+	}
+
+	bound := make([]string, 0, len(task.Params)+len(rest))
+
+	for i, param := range task.Params {
+		switch {
+		case param.Variadic:
+			bound = append(bound, rest...)
+		case values[i] != nil:
+			bound = append(bound, *values[i])
+		case !param.Optional:
+			return nil, usage(task, fmt.Errorf("%w: missing %q", ErrTooFewArgumentsInBlock, param.Name))
+		default:
+			bound = append(bound, param.Default)
+		}
+	}
+
+	if err := task.Check(bound); err != nil {
+		return nil, usage(task, err)
+	}
+
+	return bound, nil
+}
+
+func isVariadic(task *ast.TaskDecl) bool {
+	return len(task.Params) != 0 && task.Params[len(task.Params)-1].Variadic
+}
+
+func usage(task *ast.TaskDecl, err error) error {
+	return &UsageError{Err: err, Usage: task.Usage()}
+}
@@ -0,0 +1,102 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ricochhet/gomake/interpret"
+	"github.com/ricochhet/gomake/parser"
+)
+
+func TestBindArgs(t *testing.T) {
+	t.Parallel()
+
+	file, err := parser.ParseFile("test.gomake", `
+build(platform, jobs="1", tags?) {
+    echo {platform} {jobs} {tags}
+}
+
+test(pkg, rest...) {
+    echo {pkg} {rest}
+}
+
+none() {
+    echo none
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		task string
+		args []string
+		want []string
+		err  error
+	}{
+		{"build", []string{"linux"}, []string{"linux", "1", ""}, nil},
+		{"build", []string{"linux", "4", "net"}, []string{"linux", "4", "net"}, nil},
+		{"build", []string{"jobs=4", "linux"}, []string{"linux", "4", ""}, nil},
+		{"build", []string{"tags=net", "platform=darwin"}, []string{"darwin", "1", "net"}, nil},
+		{"build", []string{"linux", "tags=a=b"}, []string{"linux", "1", "a=b"}, nil},
+		{"build", []string{"jobs=", "linux"}, []string{"linux", "", ""}, nil},
+		{"build", []string{"a:b=c"}, []string{"a:b=c", "1", ""}, nil},
+		{"build", []string{"linux", "--x=1"}, []string{"linux", "--x=1", ""}, nil},
+		{"build", []string{}, nil, interpret.ErrTooFewArgumentsInBlock},
+		{"build", []string{"jobs=2"}, nil, interpret.ErrTooFewArgumentsInBlock},
+		{"build", []string{"linux", "4", "net", "extra"}, nil, interpret.ErrTooManyArguments},
+		{"build", []string{"platform=a", "platform=b"}, nil, interpret.ErrDuplicateArgument},
+		{"build", []string{"linux", "platform=b"}, []string{"b", "linux", ""}, nil},
+		{"build", []string{"nope=1"}, nil, interpret.ErrUnknownParameter},
+		{"test", []string{"./a"}, []string{"./a"}, nil},
+		{"test", []string{"./a", "./b", "./c"}, []string{"./a", "./b", "./c"}, nil},
+		{"test", []string{"./a", "rest=-v", "./c"}, []string{"./a", "-v", "./c"}, nil},
+		{"test", []string{"./a", "GOFLAGS=-x"}, []string{"./a", "GOFLAGS=-x"}, nil},
+		{"test", []string{"rest=x", "pkg=./a"}, []string{"./a", "x"}, nil},
+		{"test", []string{}, nil, interpret.ErrTooFewArgumentsInBlock},
+		{"none", []string{}, []string{}, nil},
+		{"none", []string{"x"}, nil, interpret.ErrTooManyArguments},
+		{"none", []string{"x=1"}, nil, interpret.ErrUnknownParameter},
+	}
+
+	for _, test := range tests {
+		got, err := interpret.BindArgs(file.Task(test.task), test.args)
+		if test.err == nil {
+			if err != nil || !slices.Equal(got, test.want) {
+				t.Errorf("BindArgs(%s, %q) = %q, %v, want %q", test.task, test.args, got, err, test.want)
+			}
+
+			continue
+		}
+
+		var usageErr *interpret.UsageError
+		if !errors.Is(err, test.err) || !errors.As(err, &usageErr) {
+			t.Errorf("BindArgs(%s, %q) = %v, want a usage error for %v", test.task, test.args, err, test.err)
+			continue
+		}
+
+		if !strings.HasPrefix(usageErr.Usage, test.task) || !strings.Contains(err.Error(), "usage: gomake "+test.task) {
+			t.Errorf("BindArgs(%s, %q) = %v, want the usage of %s", test.task, test.args, err, test.task)
+		}
+	}
+}
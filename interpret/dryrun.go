@@ -0,0 +1,108 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ricochhet/gomake/object"
+	"github.com/ricochhet/gomake/process"
+	"github.com/ricochhet/gomake/token"
+)
+
+// SetDryRun makes the interpreter print the commands it would run to w instead of running them.
+// Tasks run one at a time so the plan reads in execution order.
+func (in *Interpreter) SetDryRun(w io.Writer) {
+	in.DryRun = true
+	in.Jobs = 1
+	in.plan = w
+	in.Runner = func(_ context.Context, cmd object.Command) error {
+		return PrintCommand(w, cmd)
+	}
+}
+
+// PrintSkip reports that the command or task at pos would be skipped, and why.
+func PrintSkip(w io.Writer, task string, pos token.Position, reason error) {
+	fmt.Fprintf(w, "gomake: would skip %s (%s): %v\n", task, pos, reason)
+}
+
+// PrintCommand describes cmd as it would be executed, or why its guards skip it.
+func PrintCommand(w io.Writer, cmd object.Command) error {
+	if err := Check(cmd); err != nil {
+		PrintSkip(w, cmd.Task, cmd.Position, err)
+		return nil
+	}
+
+	shell, flag := process.Shell()
+
+	fmt.Fprintf(w, "gomake: would run %s (%s)\n", cmd.Task, cmd.Position)
+
+	if cmd.Script {
+		fmt.Fprintf(w, "\tscript:\n\t\t%s\n", strings.ReplaceAll(cmd.Command, "\n", "\n\t\t"))
+	} else {
+		fmt.Fprintf(w, "\tcommand:     %s\n", cmd.Command)
+	}
+
+	if cmd.Capture != "" {
+		fmt.Fprintf(w, "\tcapture:     %s\n", cmd.Capture)
+		fmt.Fprintf(cmd.Stdout, "$(%s)", cmd.Command)
+	}
+
+	if cmd.Attempts > 1 {
+		fmt.Fprintf(w, "\tretry:       %d attempts, %s backoff\n", cmd.Attempts, cmd.Backoff)
+	}
+
+	if cmd.Ignore {
+		fmt.Fprintf(w, "\terrors:      ignored\n")
+	}
+
+	if cmd.Timeout > 0 {
+		fmt.Fprintf(w, "\ttimeout:     %s\n", cmd.Timeout)
+	}
+
+	fmt.Fprintf(w, "\tdirectory:   %s\n", cmd.Directory)
+	fmt.Fprintf(w, "\tshell:       %s %s\n", shell, flag)
+
+	for _, env := range EnvironmentDelta(cmd.Environment) {
+		fmt.Fprintf(w, "\tenvironment: %s\n", env)
+	}
+
+	return nil
+}
+
+// EnvironmentDelta lists the variables of environment that differ from the process environment.
+func EnvironmentDelta(environment []string) []string {
+	delta := make([]string, 0, len(environment))
+
+	for _, env := range environment {
+		key, value, _ := strings.Cut(env, "=")
+		if current, ok := os.LookupEnv(key); !ok || current != value {
+			delta = append(delta, env)
+		}
+	}
+
+	slices.Sort(delta)
+
+	return delta
+}
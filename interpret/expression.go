@@ -0,0 +1,113 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ricochhet/gomake/expr"
+	"github.com/ricochhet/gomake/object"
+	"github.com/ricochhet/gomake/token"
+)
+
+// guardKinds orders the kinds of guards a frame combines into its expression.
+//
+//nolint:gochecknoglobals // wontfix
+var guardKinds = []string{"compare", "when", "defined", "exists", "is_dir", "is_file", "newer", "glob_any"}
+
+// SetGuard replaces the guard of a kind and combines the guards of all kinds into the
+// expression deciding whether the following commands run.
+func (f *Frame) SetGuard(kind string, guard object.Expression) {
+	f.Guards[kind] = guard
+
+	sources := make([]string, 0, len(f.Guards))
+	result := true
+
+	for _, kind := range guardKinds {
+		if guard, ok := f.Guards[kind]; ok {
+			sources = append(sources, guard.Source)
+			result = result && guard.Result
+		}
+	}
+
+	f.Expression = object.Expression{Source: strings.Join(sources, " && "), Result: result}
+}
+
+// Compare guards commands on two operands being equal, or different if equal is false.
+func Compare(operands []string, equal bool) object.Expression {
+	if equal {
+		return object.Expression{Source: fmt.Sprintf("%q == %q", operands[0], operands[1]), Result: operands[0] == operands[1]}
+	}
+
+	return object.Expression{Source: fmt.Sprintf("%q != %q", operands[0], operands[1]), Result: operands[0] != operands[1]}
+}
+
+// When guards commands on an expression, evaluated once where the guard is declared.
+func (f *Frame) When(source string, pos token.Position) (object.Expression, error) {
+	node, err := expr.Parse(source, pos)
+	if err != nil {
+		return object.Expression{}, err
+	}
+
+	ok, err := expr.Test(node, f.Env())
+	if err != nil {
+		return object.Expression{}, err
+	}
+
+	return object.Expression{Source: source, Result: ok}, nil
+}
+
+// Env evaluates expressions in the frame: quoted strings are expanded and variables
+// set by @(env) take precedence over the process environment.
+func (f *Frame) Env() *expr.Env {
+	return &expr.Env{
+		Expand: f.Expand,
+		Getenv: func(key string) (string, bool) {
+			for i := len(f.Environment) - 1; i >= 0; i-- {
+				if name, value, _ := strings.Cut(f.Environment[i], "="); name == key {
+					return value, true
+				}
+			}
+
+			return os.LookupEnv(key)
+		},
+		Dir: f.Directory,
+	}
+}
+
+// Predicate guards commands on a filesystem predicate such as @(exists:"path").
+func (f *Frame) Predicate(name string, args []string) (object.Expression, error) {
+	values := make([]any, 0, len(args))
+	quoted := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		values = append(values, arg)
+		quoted = append(quoted, strconv.Quote(arg))
+	}
+
+	result, err := expr.Functions[name].Call(f.Env(), values)
+	if err != nil {
+		return object.Expression{}, err
+	}
+
+	return object.Expression{Source: name + "(" + strings.Join(quoted, ", ") + ")", Result: expr.Truth(result)}, nil
+}
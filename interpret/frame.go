@@ -0,0 +1,335 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/expr"
+	"github.com/ricochhet/gomake/object"
+	"github.com/ricochhet/gomake/process"
+	"github.com/ricochhet/gomake/token"
+	"github.com/ricochhet/gomake/util"
+)
+
+// Frame is a single task invocation. Parameters are bound per frame and
+// directives only affect the commands of the frame they appear in.
+type Frame struct {
+	Task        *ast.TaskDecl
+	Args        []string
+	Parent      *Frame
+	Call        token.Position
+	OS          string
+	Arch        string
+	Directory   string
+	Expression  object.Expression
+	Guards      map[string]object.Expression
+	Environment []string
+	Vars        map[string]string
+	Captured    map[string]bool
+	Globals     map[string]string
+}
+
+// StackError is a failure inside a task, annotated with the frames that led to it.
+type StackError struct {
+	Err   error
+	Trace []string
+}
+
+func NewFrame(task *ast.TaskDecl, args []string, parent *Frame, call token.Position, cwd string, globals map[string]string) *Frame {
+	return &Frame{
+		Task:        task,
+		Args:        task.Bind(args),
+		Parent:      parent,
+		Call:        call,
+		OS:          "all",
+		Arch:        "all",
+		Directory:   cwd,
+		Expression:  object.Expression{Source: "", Result: true},
+		Guards:      make(map[string]object.Expression),
+		Environment: make([]string, 0),
+		Vars:        make(map[string]string),
+		Captured:    make(map[string]bool),
+		Globals:     globals,
+	}
+}
+
+func (e *StackError) Error() string {
+	return e.Err.Error() + "\n\tat " + strings.Join(e.Trace, "\n\tat ")
+}
+
+func (e *StackError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap annotates err with the stack of frames ending at pos.
+func (f *Frame) Wrap(pos token.Position, err error) error {
+	var stackErr *StackError
+	if errors.As(err, &stackErr) {
+		return err
+	}
+
+	trace := make([]string, 0)
+
+	for frame := f; frame != nil; frame = frame.Parent {
+		trace = append(trace, fmt.Sprintf("%s (%s)", frame.Signature(), pos))
+		pos = frame.Call
+	}
+
+	return &StackError{Err: err, Trace: trace}
+}
+
+func (f *Frame) Signature() string {
+	args := make([]string, 0, len(f.Args))
+	for _, arg := range f.Args {
+		args = append(args, strconv.Quote(arg))
+	}
+
+	return f.Task.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Expand substitutes declared variables, task parameters, variables set by @(env) and
+// process environment variables in text.
+// Captured values are inserted last, so that they are used as they are.
+func (f *Frame) Expand(text string) (string, error) {
+	captured := make([]string, 0)
+
+	text, err := f.expandVariables(text, []string{}, &captured)
+	if err != nil {
+		return "", err
+	}
+
+	text, err = object.SetKeyValueVariables(object.SetFunctionParams(text, f.Task.ParamNames(), f.Task.Values(f.Args)), f.Environment)
+	if err != nil {
+		return "", err
+	}
+
+	return insertCaptured(object.SetEnvironmentVariables(text), captured), nil
+}
+
+func (f *Frame) ExpandAll(texts []string) ([]string, error) {
+	values := make([]string, 0, len(texts))
+
+	for _, text := range texts {
+		value, err := f.Expand(text)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// Apply evaluates a directive. Guards replace the previous guard of the same kind, guards
+// of different kinds must all hold, and they only decide whether the commands following them run.
+func (f *Frame) Apply(stmt *ast.DirectiveStmt, cwd string) error {
+	if stmt.Name == "when" {
+		expression, err := f.When(stmt.Args[0], stmt.Pos)
+		f.SetGuard(stmt.Name, expression)
+
+		return err
+	}
+
+	args, err := f.ExpandAll(stmt.Args)
+	if err != nil {
+		return err
+	}
+
+	switch stmt.Name {
+	case "cd":
+		f.SetDirectory(args[0], cwd)
+	case "os":
+		f.OS, err = args[0], util.CheckOS(args[0])
+	case "arch":
+		f.Arch, err = args[0], util.CheckArch(args[0])
+	case "platform":
+		err = f.SetPlatform(args[0])
+	case "eq", "neq":
+		f.SetGuard("compare", Compare(args, stmt.Name == "eq"))
+	case "env":
+		f.SetEnvironment(args)
+	case "exists", "is_dir", "is_file", "newer", "glob_any":
+		var expression object.Expression
+
+		expression, err = f.Predicate(stmt.Name, args)
+		f.SetGuard(stmt.Name, expression)
+	case "ifdef", "ifndef":
+		var expression object.Expression
+
+		expression, err = f.Predicate("defined", args)
+		if stmt.Name == "ifndef" {
+			expression = object.Expression{Source: "!" + expression.Source, Result: !expression.Result}
+		}
+
+		f.SetGuard("defined", expression)
+	}
+
+	return err
+}
+
+// Directive returns the last task level directive called name, or nil if the task
+// does not declare it.
+func (f *Frame) Directive(name string) *ast.DirectiveStmt {
+	var last *ast.DirectiveStmt
+
+	for _, stmt := range f.Task.Body {
+		if directive, ok := stmt.(*ast.DirectiveStmt); ok && directive.Name == name {
+			last = directive
+		}
+	}
+
+	return last
+}
+
+// Timeout returns the duration set by a task level @(timeout), or 0 if there is none.
+func (f *Frame) Timeout() (time.Duration, error) {
+	directive := f.Directive("timeout")
+	if directive == nil {
+		return 0, nil
+	}
+
+	return f.Duration(directive.Args[0])
+}
+
+// Retry expands and parses the attempts and backoff of a @(retry). Without arguments
+// the command or task is attempted once.
+func (f *Frame) Retry(args []string) (process.Retry, error) {
+	retry := process.Retry{Attempts: 1, Backoff: process.DefaultBackoff}
+	if len(args) == 0 {
+		return retry, nil
+	}
+
+	values, err := f.ExpandAll(args)
+	if err != nil {
+		return retry, err
+	}
+
+	if retry.Attempts, err = strconv.Atoi(values[0]); err != nil || retry.Attempts < 1 {
+		return retry, fmt.Errorf("%w attempts %q", ErrInvalidRetry, values[0])
+	}
+
+	if len(values) > 1 {
+		if retry.Backoff, err = time.ParseDuration(values[1]); err != nil || retry.Backoff < 0 {
+			return retry, fmt.Errorf("%w backoff %q", ErrInvalidRetry, values[1])
+		}
+	}
+
+	return retry, nil
+}
+
+// Duration expands and parses the duration of a timeout.
+func (f *Frame) Duration(text string) (time.Duration, error) {
+	value, err := f.Expand(text)
+	if err != nil {
+		return 0, err
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%w %q", ErrInvalidTimeout, value)
+	}
+
+	return duration, nil
+}
+
+// Condition evaluates the condition of an if block.
+func (f *Frame) Condition(cond *ast.Condition) (bool, error) {
+	return expr.Test(cond.Expr, f.Env())
+}
+
+// Scope saves the directive state of the frame and the variables named in locals, and
+// returns a function restoring them. Other variables, such as captured output, are kept.
+func (f *Frame) Scope(locals []string) func() {
+	osName, arch, directory, expression, environment := f.OS, f.Arch, f.Directory, f.Expression, f.Environment
+	guards := maps.Clone(f.Guards)
+	saved, captured := make(map[string]string, len(locals)), make(map[string]bool, len(locals))
+
+	for _, name := range locals {
+		if value, ok := f.Vars[name]; ok {
+			saved[name], captured[name] = value, f.Captured[name]
+		}
+	}
+
+	return func() {
+		f.OS, f.Arch, f.Directory, f.Expression, f.Environment = osName, arch, directory, expression, environment
+		f.Guards = guards
+
+		for _, name := range locals {
+			if value, ok := saved[name]; ok {
+				f.Vars[name], f.Captured[name] = value, captured[name]
+			} else {
+				delete(f.Vars, name)
+				delete(f.Captured, name)
+			}
+		}
+	}
+}
+
+// Snapshot saves the directive state and every variable of the frame, and returns a
+// function restoring them so that the body of a task can start over.
+func (f *Frame) Snapshot() func() {
+	restore, vars, captured := f.Scope(nil), maps.Clone(f.Vars), maps.Clone(f.Captured)
+
+	return func() {
+		restore()
+
+		f.Vars, f.Captured = vars, captured
+	}
+}
+
+// SetPlatform guards commands on an os/arch pair, or clears both guards for "all".
+func (f *Frame) SetPlatform(platform string) error {
+	if err := util.CheckPlatform(platform); err != nil {
+		return err
+	}
+
+	f.OS, f.Arch, _ = strings.Cut(platform, "/")
+	if f.Arch == "" {
+		f.Arch = "all"
+	}
+
+	return nil
+}
+
+func (f *Frame) SetDirectory(directory string, cwd string) {
+	if directory == "" {
+		f.Directory = cwd
+	} else {
+		f.Directory = directory
+	}
+}
+
+func (f *Frame) SetEnvironment(variables []string) {
+	envMap := util.SliceToMap(f.Environment)
+	varMap := util.SliceToMap(variables)
+
+	for k, v := range varMap {
+		envMap[k] = v
+	}
+
+	f.Environment = util.MapToSlice(envMap)
+}
@@ -0,0 +1,137 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ricochhet/gomake/interpret"
+	"github.com/ricochhet/gomake/object"
+)
+
+// record runs task of text and returns the commands it would run, without running them.
+func record(t *testing.T, text, task string, args ...string) []object.Command {
+	t.Helper()
+
+	in, err := interpret.Interpret("test.gomake", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := make([]object.Command, 0)
+
+	in.Cwd = "/work"
+	in.Cache = nil
+	in.Runner = func(_ context.Context, cmd object.Command) error {
+		commands = append(commands, cmd)
+		return nil
+	}
+
+	if err := in.Run(context.Background(), task, args); err != nil {
+		t.Fatal(err)
+	}
+
+	return commands
+}
+
+func TestFrameScope(t *testing.T) {
+	t.Parallel()
+
+	commands := record(t, `
+a() {
+    @(cd:"x")
+    @(env:"A=1")
+    echo a1
+    @b
+    echo a2
+    if os != "plan9" {
+        @(cd:"z")
+        @(env:"C=3")
+        echo a3
+    }
+    echo a4
+}
+
+b() {
+    echo b1
+    @(cd:"y")
+    @(env:"B=2")
+    echo b2
+}
+`, "a")
+
+	tests := []struct {
+		command     string
+		directory   string
+		environment []string
+	}{
+		{"echo a1", "x", []string{"A=1"}},
+		{"echo b1", "/work", []string{}},
+		{"echo b2", "y", []string{"B=2"}},
+		{"echo a2", "x", []string{"A=1"}},
+		{"echo a3", "z", []string{"A=1", "C=3"}},
+		{"echo a4", "x", []string{"A=1"}},
+	}
+
+	if len(commands) != len(tests) {
+		t.Fatalf("ran %d commands, want %d", len(commands), len(tests))
+	}
+
+	for i, test := range tests {
+		cmd := commands[i]
+
+		environment := slices.Clone(cmd.Environment)
+		slices.Sort(environment)
+
+		if cmd.Command != test.command || cmd.Directory != test.directory || !slices.Equal(environment, test.environment) {
+			t.Errorf("command %d = %q in %q with %q, want %q in %q with %q",
+				i, cmd.Command, cmd.Directory, environment, test.command, test.directory, test.environment)
+		}
+	}
+}
+
+func TestCallGuards(t *testing.T) {
+	t.Parallel()
+
+	commands := record(t, `
+a() {
+    @(os:"windows")
+    @(arch:"wasm")
+    @deploy
+    @(platform:"all")
+    @(when:"os == 'plan9'")
+    @deploy
+    @(when:"os != 'plan9'")
+    @(ifdef:"GOMAKE_NOT_SET")
+    @deploy
+    @(ifndef:"GOMAKE_NOT_SET")
+    @deploy("last")
+}
+
+deploy(name="first") {
+    echo {name}
+}
+`, "a")
+
+	if len(commands) != 1 || commands[0].Command != "echo last" {
+		t.Errorf("commands = %+v, want only the last call to run", commands)
+	}
+}
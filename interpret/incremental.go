@@ -0,0 +1,134 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/cache"
+	"github.com/ricochhet/gomake/util"
+)
+
+var ErrInputNotFound = errors.New("input not found")
+
+// Incremental holds the @(inputs), @(outputs) and @(cache) directives of a task.
+type Incremental struct {
+	Inputs  []string
+	Outputs []string
+	Mode    string
+}
+
+// Incremental collects the task level directives of the frame, or nil if the task declares
+// neither inputs nor outputs. Paths are relative to the directory gomake runs in.
+func (f *Frame) Incremental() (*Incremental, error) {
+	inc := &Incremental{Inputs: []string{}, Outputs: []string{}, Mode: ""}
+
+	for _, stmt := range f.Task.Body {
+		directive, ok := stmt.(*ast.DirectiveStmt)
+		if !ok || !slices.Contains([]string{"inputs", "outputs", "cache"}, directive.Name) {
+			continue
+		}
+
+		args, err := f.ExpandAll(directive.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		switch directive.Name {
+		case "inputs":
+			inc.Inputs = append(inc.Inputs, args...)
+		case "outputs":
+			inc.Outputs = append(inc.Outputs, args...)
+		case "cache":
+			inc.Mode = args[0]
+		}
+	}
+
+	if len(inc.Inputs) == 0 && len(inc.Outputs) == 0 {
+		return nil, nil //nolint:nilnil // wontfix
+	}
+
+	if inc.Mode == "" {
+		inc.Mode = "hash"
+		if len(inc.Outputs) != 0 {
+			inc.Mode = "mtime"
+		}
+	}
+
+	return inc, nil
+}
+
+// Files expands the input patterns into the sorted list of matching files.
+func (inc *Incremental) Files() ([]string, error) {
+	files := make([]string, 0)
+
+	for _, input := range inc.Inputs {
+		matches, err := util.Glob(input)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matches) == 0 && !strings.ContainsAny(input, "*?[") {
+			return nil, fmt.Errorf("%w: %s", ErrInputNotFound, input)
+		}
+
+		files = append(files, matches...)
+	}
+
+	slices.Sort(files)
+
+	return slices.Compact(files), nil
+}
+
+// UpToDate reports whether the task can be skipped, along with the input hash to record
+// once it succeeds in hash mode.
+func (inc *Incremental) UpToDate(store *cache.Cache, key string) (bool, string, error) {
+	files, err := inc.Files()
+	if err != nil {
+		return false, "", err
+	}
+
+	if inc.Mode == "mtime" {
+		newer, err := cache.Newer(inc.Outputs, files)
+		return newer, "", err
+	}
+
+	hash, err := cache.Hash(files)
+	if err != nil {
+		return false, "", err
+	}
+
+	previous, err := store.Get(key)
+	if err != nil || previous != hash {
+		return false, hash, err
+	}
+
+	for _, output := range inc.Outputs {
+		if _, err := os.Stat(output); err != nil {
+			return false, hash, nil //nolint:nilerr // a missing output means the task has to run
+		}
+	}
+
+	return true, hash, nil
+}
@@ -19,25 +19,484 @@
 package interpret
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/cache"
 	"github.com/ricochhet/gomake/object"
 	"github.com/ricochhet/gomake/parser"
+	"github.com/ricochhet/gomake/process"
+	"github.com/ricochhet/gomake/scheduler"
+	"github.com/ricochhet/gomake/util"
+)
+
+var (
+	ErrTooFewArgumentsInBlock      = errors.New("too few arguments in block")
+	ErrInvalidPlatformArchitecture = errors.New("invalid platform architecture")
+	ErrInvalidTimeout              = errors.New("invalid timeout")
+	ErrInvalidRetry                = errors.New("invalid retry")
+	errFalseGuard                  = errors.New("guard is false")
+	errUpToDate                    = errors.New("up to date")
 )
 
-func Interpret(text string, fname string, args []string) (object.FunctionBlock, error) {
-	blocks, err := parser.ParseText(text)
+// Interpreter runs tasks of a parsed file, evaluating directives and calls as it goes.
+// Tasks listed in needs(...) form a graph whose independent nodes run in parallel.
+type Interpreter struct {
+	File      *ast.File
+	Cwd       string
+	Runner    func(ctx context.Context, cmd object.Command) error
+	Jobs      int
+	KeepGoing bool
+	Cache     *cache.Cache
+	DryRun    bool
+	Grace     time.Duration
+	Timeout   time.Duration
+	plan      io.Writer
+	graph     *scheduler.Graph
+	target    *scheduler.Node
+	mu        sync.Mutex
+	failures  []Failure
+}
+
+func Interpret(filename, text string) (*Interpreter, error) {
+	file, err := parser.ParseFile(filename, text)
 	if err != nil {
-		return object.FunctionBlock{}, err
+		return nil, err
 	}
 
-	block, err := object.GetBlock(blocks, fname)
+	return New(file)
+}
+
+func New(file *ast.File) (*Interpreter, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Interpreter{
+		File:      file,
+		Cwd:       cwd,
+		Runner:    Execute,
+		Jobs:      runtime.GOMAXPROCS(0),
+		KeepGoing: false,
+		Cache:     cache.New(filepath.Join(cwd, ".gomake", "cache")),
+		DryRun:    false,
+		Grace:     process.DefaultGrace,
+		Timeout:   0,
+		plan:      io.Discard,
+		graph:     scheduler.NewGraph(),
+		target:    nil,
+		mu:        sync.Mutex{},
+		failures:  []Failure{},
+	}, nil
+}
+
+// Execute runs cmd unless it is guarded by a different operating system or a false expression,
+// in which case only cmd is skipped.
+func Execute(ctx context.Context, cmd object.Command) error {
+	if Check(cmd) != nil {
+		return nil
+	}
+
+	return process.Exec(ctx, cmd)
+}
+
+// Check reports why the guards of cmd keep it from running.
+func Check(cmd object.Command) error {
+	return checkGuards(cmd.OS, cmd.Arch, cmd.Expression)
+}
+
+func checkGuards(osName, arch string, expression object.Expression) error {
+	if runtime.GOOS != osName && osName != "all" {
+		return fmt.Errorf("%w: %q does not match %q", ErrInvalidPlatformArchitecture, osName, runtime.GOOS)
+	}
+
+	if runtime.GOARCH != arch && arch != "all" {
+		return fmt.Errorf("%w: %q does not match %q", ErrInvalidPlatformArchitecture, arch, runtime.GOARCH)
+	}
+
+	if !expression.Result {
+		return fmt.Errorf("%w: %s", errFalseGuard, expression.Source)
+	}
+
+	return nil
+}
+
+func (in *Interpreter) Run(ctx context.Context, fname string, args []string) error {
+	frame, err := in.root(fname, args)
+	if err != nil {
+		return err
+	}
+
+	node, err := in.node(frame)
+	if err != nil {
+		return err
+	}
+
+	in.target = node
+
+	if in.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeoutCause(ctx, in.Timeout, &process.TimeoutError{Command: "", Timeout: in.Timeout, Scope: "-timeout"})
+		defer cancel()
+	}
+
+	return in.graph.Run(ctx, node, in.Jobs, in.KeepGoing)
+}
+
+// Dump collects the commands the task would run, in order, without checking their guards.
+func (in *Interpreter) Dump(fname string, args []string) (object.FunctionBlock, error) {
+	block := object.FunctionBlock{Name: fname, Params: []string{}, Commands: make([]object.Command, 0)}
+	dump := &Interpreter{File: in.File, Cwd: in.Cwd, Runner: func(_ context.Context, cmd object.Command) error {
+		block.Commands = append(block.Commands, cmd)
+		return nil
+	}, Jobs: 1, KeepGoing: false, Cache: nil, DryRun: false, Grace: in.Grace, Timeout: 0,
+		plan: io.Discard, graph: scheduler.NewGraph(), target: nil, mu: sync.Mutex{}, failures: []Failure{}}
+
+	frame, err := dump.root(fname, args)
 	if err != nil {
 		return object.FunctionBlock{}, err
 	}
 
-	parsedStatefulBlock, err := parser.ParseStatefulBlock(block, args)
+	node, err := dump.node(frame)
 	if err != nil {
 		return object.FunctionBlock{}, err
 	}
 
-	return parser.ParseBlock(parsedStatefulBlock), nil
+	if err := dump.graph.Ensure(context.Background(), node); err != nil {
+		return object.FunctionBlock{}, err
+	}
+
+	return block, nil
+}
+
+func (in *Interpreter) root(fname string, args []string) (*Frame, error) {
+	task := in.File.Task(fname)
+	if task == nil {
+		return nil, &NotFoundError{Name: fname, Suggestion: util.Suggest(fname, in.File.TaskNames())}
+	}
+
+	args, err := BindArgs(task, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFrame(task, args, nil, task.Pos, in.Cwd, Globals(in.File)), nil
+}
+
+// node registers frame in the graph, along with the dependencies it needs.
+func (in *Interpreter) node(frame *Frame) (*scheduler.Node, error) {
+	if node := in.graph.Node(frame.Signature()); node != nil {
+		return node, nil
+	}
+
+	deps := make([]*scheduler.Node, 0, len(frame.Task.Needs))
+
+	for _, need := range frame.Task.Needs {
+		callee, err := in.frame(frame, need)
+		if err != nil {
+			return nil, frame.Wrap(need.Pos, err)
+		}
+
+		dep, err := in.node(callee)
+		if err != nil {
+			return nil, err
+		}
+
+		deps = append(deps, dep)
+	}
+
+	return in.graph.Add(frame.Signature(), deps, func(ctx context.Context) error {
+		return in.runBody(ctx, frame)
+	}), nil
+}
+
+// runFrame runs the dependencies of a called task, unless they already ran, followed by its body.
+// With KeepGoing set every dependency runs before a failure keeps the body from running.
+func (in *Interpreter) runFrame(ctx context.Context, frame *Frame) error {
+	errs := make([]error, 0)
+
+	for _, need := range frame.Task.Needs {
+		callee, err := in.frame(frame, need)
+		if err != nil {
+			return frame.Wrap(need.Pos, err)
+		}
+
+		node, err := in.node(callee)
+		if err != nil {
+			return err
+		}
+
+		if err := in.graph.Ensure(ctx, node); err != nil {
+			if !in.KeepGoing || ctx.Err() != nil {
+				return frame.Wrap(need.Pos, err)
+			}
+
+			errs = append(errs, frame.Wrap(need.Pos, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	return in.runBody(ctx, frame)
+}
+
+// runBody runs the statements of a task, unless its declared outputs are up to date.
+func (in *Interpreter) runBody(ctx context.Context, frame *Frame) error {
+	skip, hash, err := in.upToDate(frame)
+	if err != nil {
+		return frame.Wrap(frame.Task.Pos, err)
+	}
+
+	if skip && in.DryRun {
+		PrintSkip(in.plan, frame.Signature(), frame.Task.Pos, errUpToDate)
+		return nil
+	}
+
+	if skip {
+		fmt.Printf("gomake: %s is up to date\n", frame.Signature())
+		return nil
+	}
+
+	timeout, err := frame.Timeout()
+	if err != nil {
+		return frame.Wrap(frame.Task.Pos, err)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		scope := "task " + frame.Signature()
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, &process.TimeoutError{Command: "", Timeout: timeout, Scope: scope})
+		defer cancel()
+	}
+
+	var retryArgs []string
+	if directive := frame.Directive("retry"); directive != nil {
+		retryArgs = directive.Args
+	}
+
+	retry, err := frame.Retry(retryArgs)
+	if err != nil {
+		return frame.Wrap(frame.Task.Pos, err)
+	}
+
+	if err := retry.Do(ctx, "task "+frame.Signature(), func() error {
+		defer frame.Snapshot()()
+
+		return in.runStmts(ctx, frame, frame.Task.Body)
+	}); err != nil {
+		return err
+	}
+
+	if hash != "" && !in.DryRun {
+		if err := in.Cache.Put(frame.Signature(), hash); err != nil {
+			return frame.Wrap(frame.Task.Pos, err)
+		}
+	}
+
+	return nil
+}
+
+// runStmts runs stmts in order until one fails. With KeepGoing set a failed call to another
+// task does not stop the statements that follow it, and the failures are returned at the end.
+func (in *Interpreter) runStmts(ctx context.Context, frame *Frame, stmts []ast.Stmt) error {
+	errs := make([]error, 0)
+
+	for _, stmt := range stmts {
+		var err error
+
+		switch stmt := stmt.(type) {
+		case *ast.CommandStmt:
+			err = in.runCommand(ctx, frame, stmt)
+		case *ast.DirectiveStmt:
+			err = frame.Apply(stmt, in.Cwd)
+		case *ast.CallStmt:
+			err = in.call(ctx, frame, stmt)
+		case *ast.IfStmt:
+			err = in.runIf(ctx, frame, stmt)
+		case *ast.VarDecl:
+			frame.SetVariable(stmt.Name, stmt.Value)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		switch stmt.(type) {
+		case *ast.CallStmt, *ast.IfStmt:
+			// Failures inside called tasks and if blocks are recorded where they happen.
+		default:
+			in.fail(frame, stmt.Position(), err)
+		}
+
+		if _, ok := stmt.(*ast.CallStmt); !ok || !in.KeepGoing || ctx.Err() != nil {
+			return errors.Join(append(errs, frame.Wrap(stmt.Position(), err))...)
+		}
+
+		errs = append(errs, frame.Wrap(stmt.Position(), err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// runIf runs the branch selected by the condition. Directives and variables declared inside
+// the branch end with it, while captured variables are kept.
+func (in *Interpreter) runIf(ctx context.Context, frame *Frame, stmt *ast.IfStmt) error {
+	ok, err := frame.Condition(stmt.Cond)
+	if err != nil {
+		return err
+	}
+
+	branch := stmt.Else
+	if ok {
+		branch = stmt.Then
+	}
+
+	defer frame.Scope(declared(branch))()
+
+	return in.runStmts(ctx, frame, branch)
+}
+
+// declared returns the names of the variables declared directly in stmts.
+func declared(stmts []ast.Stmt) []string {
+	names := make([]string, 0)
+
+	for _, stmt := range stmts {
+		if decl, ok := stmt.(*ast.VarDecl); ok {
+			names = append(names, decl.Name)
+		}
+	}
+
+	return names
+}
+
+func (in *Interpreter) upToDate(frame *Frame) (bool, string, error) {
+	if in.Cache == nil {
+		return false, "", nil
+	}
+
+	inc, err := frame.Incremental()
+	if err != nil || inc == nil {
+		return false, "", err
+	}
+
+	return inc.UpToDate(in.Cache, frame.Signature())
+}
+
+func (in *Interpreter) runCommand(ctx context.Context, frame *Frame, stmt *ast.CommandStmt) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	command, err := frame.Expand(stmt.Text)
+	if err != nil {
+		return err
+	}
+
+	var timeout time.Duration
+
+	if stmt.Timeout != "" {
+		if timeout, err = frame.Duration(stmt.Timeout); err != nil {
+			return err
+		}
+	}
+
+	retry, err := frame.Retry(stmt.Retry)
+	if err != nil {
+		return err
+	}
+
+	var stdout io.Writer
+
+	output := &strings.Builder{}
+	if stmt.Capture != "" {
+		stdout = output
+	}
+
+	ignore := stmt.IgnoreErrors || frame.Directive("ignore_errors") != nil
+	cmd := object.Command{
+		OS:          frame.OS,
+		Arch:        frame.Arch,
+		Directory:   frame.Directory,
+		Command:     command,
+		Script:      stmt.Script,
+		Expression:  frame.Expression,
+		Environment: frame.Environment,
+		Capture:     stmt.Capture,
+		Timeout:     timeout,
+		Attempts:    retry.Attempts,
+		Backoff:     retry.Backoff,
+		Ignore:      ignore,
+		Stdout:      stdout,
+		Task:        frame.Signature(),
+		Position:    stmt.Pos,
+		Grace:       in.Grace,
+	}
+
+	if err := retry.Do(ctx, strconv.Quote(command), func() error {
+		output.Reset()
+
+		return in.Runner(ctx, cmd)
+	}); err != nil {
+		if !ignore || ctx.Err() != nil {
+			return err
+		}
+
+		fmt.Printf("gomake: ignoring error of %q: %v\n", command, err)
+	}
+
+	// A command skipped by its guards leaves the variable as it was.
+	if stmt.Capture != "" && Check(cmd) == nil {
+		frame.SetCaptured(stmt.Capture, strings.TrimSpace(output.String()))
+	}
+
+	return nil
+}
+
+// call runs the called task in a new frame with its arguments evaluated in the caller's frame,
+// unless the guards of the caller skip it.
+func (in *Interpreter) call(ctx context.Context, frame *Frame, stmt *ast.CallStmt) error {
+	if err := checkGuards(frame.OS, frame.Arch, frame.Expression); err != nil {
+		if in.DryRun {
+			PrintSkip(in.plan, frame.Signature(), stmt.Pos, fmt.Errorf("call to %s: %w", stmt.Name, err))
+		}
+
+		return nil
+	}
+
+	callee, err := in.frame(frame, stmt)
+	if err != nil {
+		in.fail(frame, stmt.Pos, err)
+		return err
+	}
+
+	return in.runFrame(ctx, callee)
+}
+
+func (in *Interpreter) frame(frame *Frame, stmt *ast.CallStmt) (*Frame, error) {
+	args, err := frame.ExpandAll(stmt.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	task := in.File.Task(stmt.Name)
+	if err := task.Check(task.Bind(args)); err != nil {
+		return nil, err
+	}
+
+	return NewFrame(task, args, frame, stmt.Pos, in.Cwd, frame.Globals), nil
 }
@@ -0,0 +1,103 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ricochhet/gomake/scheduler"
+	"github.com/ricochhet/gomake/token"
+)
+
+// Failure is a task that failed at Pos, or that was skipped because a task it needs failed.
+type Failure struct {
+	Task    string
+	Pos     token.Position
+	Err     error
+	Skipped bool
+}
+
+// fail records a failure of frame at pos. A task that is retried fails at the same
+// position on every attempt, so only the error of the last attempt is kept.
+func (in *Interpreter) fail(frame *Frame, pos token.Position, err error) {
+	var stackErr *StackError
+	if errors.As(err, &stackErr) {
+		err = stackErr.Err
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	failure := Failure{Task: frame.Signature(), Pos: pos, Err: err, Skipped: false}
+
+	i := slices.IndexFunc(in.failures, func(f Failure) bool { return f.Task == failure.Task && f.Pos == failure.Pos })
+	if i >= 0 {
+		in.failures[i] = failure
+		return
+	}
+
+	in.failures = append(in.failures, failure)
+}
+
+// Failures lists the tasks that failed during the last run in the order they failed,
+// followed by the tasks that were skipped.
+func (in *Interpreter) Failures() []Failure {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	failures := slices.Clone(in.failures)
+
+	if in.target == nil {
+		return failures
+	}
+
+	var unknown token.Position
+
+	for _, node := range scheduler.Order(in.target) {
+		if errors.Is(node.Err, scheduler.ErrDependencyFailed) {
+			failures = append(failures, Failure{Task: node.Key, Pos: unknown, Err: node.Err, Skipped: true})
+		}
+	}
+
+	return failures
+}
+
+// PrintSummary writes a table of failures to w, with the first line of each error.
+func PrintSummary(w io.Writer, failures []Failure) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // wontfix
+
+	fmt.Fprintln(table, "TASK\tRESULT\tPOSITION\tERROR")
+
+	for _, failure := range failures {
+		result, pos := "failed", failure.Pos.String()
+		if failure.Skipped {
+			result, pos = "skipped", "-"
+		}
+
+		msg, _, _ := strings.Cut(failure.Err.Error(), "\n")
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", failure.Task, result, pos, msg)
+	}
+
+	return table.Flush()
+}
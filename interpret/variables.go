@@ -0,0 +1,114 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interpret
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/parser"
+	"github.com/ricochhet/gomake/scanner"
+)
+
+// capturedMark delimits placeholders for captured values. It cannot appear in command
+// line arguments or environment variables, so expansion never produces it by accident.
+const capturedMark = '\x00'
+
+//nolint:gochecknoglobals // wontfix
+var capturedPlaceholder = regexp.MustCompile(`\x00[0-9]+\x00`)
+
+// Globals returns the raw values of the variables declared at file level.
+func Globals(file *ast.File) map[string]string {
+	globals := make(map[string]string, len(file.Vars))
+	for _, decl := range file.Vars {
+		globals[decl.Name] = decl.Value
+	}
+
+	return globals
+}
+
+// Variable looks up a variable declared in the task, or else at file level.
+func (f *Frame) Variable(name string) (string, bool) {
+	if value, ok := f.Vars[name]; ok {
+		return value, true
+	}
+
+	value, ok := f.Globals[name]
+
+	return value, ok
+}
+
+// SetVariable declares a variable of the task, whose value is expanded when it is used.
+func (f *Frame) SetVariable(name, value string) {
+	f.Vars[name] = value
+	delete(f.Captured, name)
+}
+
+// SetCaptured stores the output of a command in a variable, which is used as it is.
+func (f *Frame) SetCaptured(name, value string) {
+	f.Vars[name] = value
+	f.Captured[name] = true
+}
+
+// expandVariables substitutes ${NAME} references to declared variables, expanding their
+// values in turn. References to names that are not declared are left for the shell, and
+// references to captured variables are replaced by a placeholder indexing captured, so
+// that their values are not expanded by later steps.
+func (f *Frame) expandVariables(text string, chain []string, captured *[]string) (string, error) {
+	return scanner.ReplaceReferences(text, func(name string) (string, bool, error) {
+		value, ok := f.Variable(name)
+		if !ok {
+			return "", false, nil
+		}
+
+		if f.Captured[name] {
+			*captured = append(*captured, value)
+			return fmt.Sprintf("%c%d%c", capturedMark, len(*captured)-1, capturedMark), true, nil
+		}
+
+		if i := slices.Index(chain, name); i != -1 {
+			cycle := append(chain[i:len(chain):len(chain)], name)
+			return "", false, fmt.Errorf("%w: %s", parser.ErrVariableCycle, strings.Join(cycle, " -> "))
+		}
+
+		value, err := f.expandVariables(value, append(chain, name), captured)
+
+		return value, true, err
+	})
+}
+
+// insertCaptured replaces the placeholders left by expandVariables with the captured values.
+func insertCaptured(text string, captured []string) string {
+	if len(captured) == 0 {
+		return text
+	}
+
+	return capturedPlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		i, err := strconv.Atoi(strings.Trim(placeholder, string(capturedMark)))
+		if err != nil || i >= len(captured) {
+			return placeholder
+		}
+
+		return captured[i]
+	})
+}
@@ -0,0 +1,70 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ricochhet/gomake/ast"
+)
+
+type listedTask struct {
+	Name        string       `json:"name"`
+	Usage       string       `json:"usage"`
+	Description string       `json:"description"`
+	Params      []*ast.Param `json:"params"`
+}
+
+// List prints every task of file in declaration order with its usage and description.
+func List(w io.Writer, file *ast.File, asJSON bool) error {
+	if asJSON {
+		tasks := make([]listedTask, 0, len(file.Tasks))
+		for _, task := range file.Tasks {
+			tasks = append(tasks, listedTask{Name: task.Name, Usage: task.Usage(), Description: task.Doc, Params: task.Params})
+		}
+
+		marshal, err := json.MarshalIndent(tasks, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(marshal))
+
+		return err
+	}
+
+	for _, task := range file.Tasks {
+		if _, err := fmt.Fprintln(w, task.Usage()); err != nil {
+			return err
+		}
+
+		if task.Doc == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintln(w, "    "+strings.ReplaceAll(task.Doc, "\n", "\n    ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -19,19 +19,55 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/ricochhet/gomake/interpret"
 	"github.com/ricochhet/gomake/process"
 )
 
-//nolint:cyclop // wontfix
 func main() {
-	if flags.Function == "" {
+	os.Exit(run())
+}
+
+// notifyContext returns a context cancelled with a process.SignalError when gomake
+// receives SIGINT or SIGTERM, which is forwarded to the running commands. A second
+// signal is no longer caught, so it terminates gomake without waiting for the commands.
+func notifyContext(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			cancel(&process.SignalError{Signal: sig})
+			signal.Stop(signals)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+//nolint:cyclop // wontfix
+func run() int {
+	if flags.Function == "" && !flags.List {
 		Errr(ErrNoFunctionName)
-		return
+		flag.Usage()
+
+		return ExitUsage
 	}
 
 	if flags.Path == "" {
@@ -40,39 +76,83 @@ func main() {
 
 	if filepath.Ext(flags.Path) != flags.Extension {
 		Errr(ErrInvalidFileType)
-		return
+		return ExitUsage
 	}
 
 	file, err := os.ReadFile(flags.Path)
-	if err != nil && flags.Path == "" {
-		Errr(ErrNoMakefilePath)
-		return
+	if errors.Is(err, fs.ErrNotExist) {
+		Errr(err)
+		return ExitNotFound
 	}
 
 	if err != nil {
 		Errr(err)
-		return
+		return ExitFailure
 	}
 
-	block, err := interpret.Interpret(string(file), flags.Function, flags.Arguments)
+	interpreter, err := interpret.Interpret(filepath.Clean(flags.Path), string(file))
 	if err != nil {
 		Errr(err)
-		return
+		Excerpt(err, string(file))
+
+		return ExitCode(err)
+	}
+
+	if flags.List {
+		if err := List(os.Stdout, interpreter.File, flags.JSON); err != nil {
+			Errr(err)
+			return ExitFailure
+		}
+
+		return ExitSuccess
 	}
 
 	if flags.Dump {
-		if data, err := Dump(block); err == nil {
-			fmt.Println(data)
-			return
+		block, err := interpreter.Dump(flags.Function, flags.Arguments)
+		if err != nil {
+			Errr(err)
+			Excerpt(err, string(file))
+
+			return ExitCode(err)
 		}
 
-		Errr(err)
+		data, err := Dump(block)
+		if err != nil {
+			Errr(err)
+			return ExitFailure
+		}
+
+		fmt.Println(data)
 
-		return
+		return ExitSuccess
 	}
 
-	if err := process.Exec(block.Commands); err != nil {
+	interpreter.Jobs = flags.Jobs
+	interpreter.KeepGoing = flags.KeepGoing
+	interpreter.Grace = flags.Grace
+	interpreter.Timeout = flags.Timeout
+
+	if flags.DryRun {
+		interpreter.SetDryRun(os.Stdout)
+	}
+
+	ctx, stop := notifyContext(context.Background())
+	defer stop()
+
+	if err := interpreter.Run(ctx, flags.Function, flags.Arguments); err != nil {
 		Errr(err)
-		return
+		Excerpt(err, string(file))
+
+		if failures := interpreter.Failures(); flags.KeepGoing && len(failures) != 0 {
+			fmt.Fprintf(os.Stderr, "\ngomake: %d tasks did not finish:\n", len(failures))
+
+			if err := interpret.PrintSummary(os.Stderr, failures); err != nil {
+				Errr(err)
+			}
+		}
+
+		return ExitCode(err)
 	}
+
+	return ExitSuccess
 }
@@ -20,29 +20,32 @@ package object
 
 import (
 	"errors"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ricochhet/gomake/scanner"
 	"github.com/ricochhet/gomake/token"
 )
 
 type Command struct {
-	OS          string     `json:"os"`
-	Directory   string     `json:"directory"`
-	Command     string     `json:"command"`
-	Expression  Expression `json:"expression"`
-	Environment []string   `json:"environment"`
-}
-
-type StatefulFunctionBlock struct {
-	Name        string     `json:"name"`
-	Params      []string   `json:"params"`
-	Commands    []Command  `json:"commands"`
-	OS          string     `json:"os"`
-	Directory   string     `json:"directory"`
-	Expression  Expression `json:"expression"`
-	Environment []string   `json:"environment"`
+	OS          string         `json:"os"`
+	Arch        string         `json:"arch"`
+	Directory   string         `json:"directory"`
+	Command     string         `json:"command"`
+	Script      bool           `json:"script"`
+	Expression  Expression     `json:"expression"`
+	Environment []string       `json:"environment"`
+	Capture     string         `json:"capture"`
+	Timeout     time.Duration  `json:"timeout"`
+	Attempts    int            `json:"attempts"`
+	Backoff     time.Duration  `json:"backoff"`
+	Ignore      bool           `json:"ignore"`
+	Stdout      io.Writer      `json:"-"`
+	Task        string         `json:"-"`
+	Position    token.Position `json:"-"`
+	Grace       time.Duration  `json:"-"`
 }
 
 type FunctionBlock struct {
@@ -52,10 +55,8 @@ type FunctionBlock struct {
 }
 
 type Expression struct {
-	OperandA  string `json:"operandA"`
-	OperandB  string `json:"operandB"`
-	Operation int    `json:"operation"`
-	Result    bool   `json:"result"`
+	Source string `json:"source"`
+	Result bool   `json:"result"`
 }
 
 var (
@@ -63,91 +64,6 @@ var (
 	ErrInvalidKeyValuePair = errors.New("invalid key=value pair")
 )
 
-func GetBlock(blocks []StatefulFunctionBlock, blockName string) (StatefulFunctionBlock, error) {
-	for _, block := range blocks {
-		if block.Name == blockName {
-			return block, nil
-		}
-	}
-
-	return StatefulFunctionBlock{}, ErrBlockNotFound
-}
-
-//nolint:cyclop // wontfix
-func (currentBlock *StatefulFunctionBlock) SetCallerBlock(blocks []StatefulFunctionBlock, callerName string, callerParams []string) error {
-	for _, block := range blocks {
-		//nolint:nestif // wontfix
-		if block.Name == callerName {
-			directory, err := SetBlockDirectory(block)
-			if err != nil {
-				return err
-			}
-
-			for _, cmd := range block.Commands {
-				commandText := cmd.Command
-				commandExpr := cmd.Expression
-
-				if len(callerParams) != 0 {
-					commandText = SetFunctionParams(cmd.Command, block.Params, callerParams)
-					commandExpr = SetOperandFunctionParams(cmd.Expression, block.Params, callerParams)
-				}
-
-				commandDirectory := cmd.Directory
-				if commandDirectory == "" {
-					commandDirectory = directory
-				}
-
-				commandOS := cmd.OS
-				if commandOS == "" {
-					commandOS = block.Directory
-				}
-
-				if commandExpr.OperandA == "" && commandExpr.OperandB == "" {
-					commandExpr = block.Expression
-				}
-
-				commandEnv := cmd.Environment
-				if len(commandEnv) == 0 {
-					commandEnv = block.Environment
-				}
-
-				currentBlock.Commands = append(currentBlock.Commands, Command{
-					Command:     commandText,
-					Directory:   commandDirectory,
-					OS:          commandOS,
-					Expression:  commandExpr,
-					Environment: commandEnv,
-				})
-			}
-
-			continue
-		}
-	}
-
-	return nil
-}
-
-func SetBlockDirectory(block StatefulFunctionBlock) (string, error) {
-	if block.Directory == "" {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return "", err
-		}
-
-		return cwd, nil
-	}
-
-	return block.Directory, nil
-}
-
-func SetBlockOperatingSystem(block StatefulFunctionBlock) string {
-	if block.OS == "" {
-		return "all"
-	}
-
-	return block.OS
-}
-
 func SetFunctionParams(original string, oldArray []string, newArray []string) string {
 	replacements := make(map[string]string)
 	for i := range oldArray {
@@ -158,14 +74,7 @@ func SetFunctionParams(original string, oldArray []string, newArray []string) st
 		original = strings.ReplaceAll(original, string(token.TokenLeftBracket)+old+string(token.TokenRightBracket), new)
 	}
 
-	return SetEnvironmentVariables(original)
-}
-
-func SetOperandFunctionParams(expr Expression, oldArray []string, newArray []string) Expression {
-	expr.OperandA = SetFunctionParams(expr.OperandA, oldArray, newArray)
-	expr.OperandB = SetFunctionParams(expr.OperandA, oldArray, newArray)
-
-	return expr
+	return original
 }
 
 func SetEnvironmentVariables(original string) string {
@@ -185,16 +94,6 @@ func SetEnvironmentVariables(original string) string {
 	return original
 }
 
-func SetArrayFunctionParams(original, oldArray, newArray []string) []string {
-	replacements := []string{}
-
-	for _, str := range original {
-		replacements = append(replacements, SetFunctionParams(str, oldArray, newArray))
-	}
-
-	return replacements
-}
-
 func SetKeyValueVariables(original string, pairs []string) (string, error) {
 	variables := scanner.ScanVariables(original)
 
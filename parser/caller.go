@@ -1,65 +1,132 @@
 package parser
 
 import (
-	"github.com/ricochhet/gomake/object"
-	"github.com/ricochhet/gomake/scanner"
+	"errors"
+	"fmt"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/token"
 )
 
-func CdCaller(s *scanner.Scanner, block *object.StatefulFunctionBlock, cwd string) error {
-	if call(s, "cd:") {
-		if err := ParseDirectory(s, block, cwd); err != nil {
-			return err
-		}
-	}
+var errUnknownDirective = errors.New("unknown directive")
 
-	return nil
+// Directives that apply to the command following them on the same line, or to the
+// whole task when they stand on a line of their own.
+//
+//nolint:gochecknoglobals // wontfix
+var modifiers = map[string]bool{
+	"timeout":       true,
+	"retry":         true,
+	"ignore_errors": true,
 }
 
-func OsCaller(s *scanner.Scanner, block *object.StatefulFunctionBlock) error {
-	if call(s, "os:") {
-		if err := ParseOperatingSystem(s, block); err != nil {
-			return err
-		}
-	}
+//nolint:gochecknoglobals // wontfix
+var directives = map[string]func(args []token.Token) error{
+	"cd":       CheckDirectory,
+	"os":       CheckOperatingSystem,
+	"arch":     CheckArchitecture,
+	"platform": CheckPlatform,
+	"ifdef":    CheckVariableName,
+	"ifndef":   CheckVariableName,
+	"eq":       CheckExpression,
+	"neq":      CheckExpression,
+	"when":     CheckWhen,
+	"env":      CheckEnvironment,
+	"capture":  CheckVariableName,
+	"timeout":  CheckTimeout,
+	"retry":    CheckRetry,
+
+	"ignore_errors": CheckNoArguments,
 
-	return nil
+	"exists":   CheckPredicate("exists"),
+	"is_dir":   CheckPredicate("is_dir"),
+	"is_file":  CheckPredicate("is_file"),
+	"newer":    CheckPredicate("newer"),
+	"glob_any": CheckPredicate("glob_any"),
+
+	"inputs":  CheckPaths,
+	"outputs": CheckPaths,
+	"cache":   CheckCacheMode,
 }
 
-func EqCaller(s *scanner.Scanner, block *object.StatefulFunctionBlock) error {
-	if call(s, "eq:") {
-		if err := ParseExpression(s, block, 0); err != nil {
-			return err
-		}
+func (p *parser) parseCaller(at token.Token) (ast.Stmt, error) {
+	if p.peek().Kind == token.LeftParen {
+		p.next()
+
+		return p.parseDirective(at)
+	}
+
+	name, err := p.expect(token.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := p.parseOptionalStrings()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &ast.CallStmt{Pos: at.Pos, Name: name.Value, Args: values(args)}, p.endOfLine()
 }
 
-func NeqCaller(s *scanner.Scanner, block *object.StatefulFunctionBlock) error {
-	if call(s, "neq:") {
-		if err := ParseExpression(s, block, 1); err != nil {
-			return err
+func (p *parser) parseDirective(at token.Token) (ast.Stmt, error) {
+	name, err := p.expect(token.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]token.Token, 0)
+
+	// A directive without arguments may leave out the colon, as in @(ignore_errors).
+	if p.peek().Kind == token.RightParen {
+		p.next()
+	} else {
+		if _, err := p.expect(token.Colon); err != nil {
+			return nil, err
+		}
+
+		if args, err = p.parseStrings(); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
-}
+	check, ok := directives[name.Value]
+	if !ok {
+		return nil, diagnostic.New(at.Pos, fmt.Errorf("%w %q", errUnknownDirective, name.Value))
+	}
+
+	if err := check(args); err != nil {
+		return nil, diagnostic.Wrap(at.Pos, err)
+	}
 
-func EnvCaller(s *scanner.Scanner, block *object.StatefulFunctionBlock) error {
-	if call(s, "env:") {
-		ParseEnvironment(s, block)
+	if next := p.peek().Kind; name.Value == "capture" || (modifiers[name.Value] && (next == token.Command || next == token.Script)) {
+		return p.parseModified(at, name.Value, args)
 	}
 
-	return nil
+	return &ast.DirectiveStmt{Pos: at.Pos, Name: name.Value, Args: values(args)}, p.endOfLine()
 }
 
-func call(s *scanner.Scanner, caller string) bool {
-	if s.PeekAhead(len(caller)) == caller {
-		s.ReadAhead(len(caller))
-		s.SkipWhitespace()
+// parseModified parses the command following a directive on the same line, as in
+// @(capture:"HASH") git rev-parse HEAD, which applies to that command only.
+func (p *parser) parseModified(at token.Token, name string, args []token.Token) (ast.Stmt, error) {
+	tok := p.next()
+	if tok.Kind != token.Command && tok.Kind != token.Script {
+		return nil, diagnostic.New(tok.Pos, fmt.Errorf("%w %s, @(%s) expects a command on the same line", ErrUnexpectedToken, tok, name))
+	}
+
+	stmt := command(tok, at.Pos)
 
-		return true
+	switch name {
+	case "capture":
+		stmt.Capture = args[0].Value
+	case "timeout":
+		stmt.Timeout = args[0].Value
+	case "retry":
+		stmt.Retry = values(args)
+	case "ignore_errors":
+		stmt.IgnoreErrors = true
 	}
 
-	return false
+	return stmt, p.endOfLine()
 }
@@ -0,0 +1,103 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package parser
+
+import (
+	"errors"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/expr"
+	"github.com/ricochhet/gomake/token"
+)
+
+var errTaskLevelDirective = errors.New("directive must be declared at task level")
+
+// Directives that describe the task as a whole and cannot be scoped to an if block.
+//
+//nolint:gochecknoglobals // wontfix
+var taskLevel = map[string]bool{
+	"inputs":  true,
+	"outputs": true,
+	"cache":   true,
+	"timeout": true,
+	"retry":   true,
+
+	"ignore_errors": true,
+}
+
+// parseIf parses if cond { ... } followed by any number of else if cond { ... }
+// and an optional else { ... }, which must start on the line of the closing bracket.
+func (p *parser) parseIf(at token.Token, owner token.Token) (*ast.IfStmt, error) {
+	cond, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(token.LeftBracket); err != nil {
+		return nil, err
+	}
+
+	then, err := p.parseBlock(owner, true)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &ast.IfStmt{Pos: at.Pos, Cond: cond, Then: then, Else: []ast.Stmt{}}
+
+	if tok := p.peek(); tok.Kind != token.Identifier || tok.Value != "else" {
+		return stmt, p.endOfLine()
+	}
+
+	p.next()
+
+	if tok := p.peek(); tok.Kind == token.Identifier && tok.Value == "if" {
+		elseIf, err := p.parseIf(p.next(), owner)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.Else = append(stmt.Else, elseIf)
+
+		return stmt, nil
+	}
+
+	if _, err := p.expect(token.LeftBracket); err != nil {
+		return nil, err
+	}
+
+	if stmt.Else, err = p.parseBlock(owner, true); err != nil {
+		return nil, err
+	}
+
+	return stmt, p.endOfLine()
+}
+
+func (p *parser) parseCondition() (*ast.Condition, error) {
+	tok, err := p.expect(token.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := expr.Parse(tok.Value, tok.Pos)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.Condition{Pos: tok.Pos, Source: tok.Value, Expr: node}, nil
+}
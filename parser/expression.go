@@ -20,46 +20,44 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 
-	"github.com/ricochhet/gomake/object"
-	"github.com/ricochhet/gomake/scanner"
+	"github.com/ricochhet/gomake/expr"
+	"github.com/ricochhet/gomake/token"
 )
 
 var ErrTooFewArgumentsInExpression = errors.New("too few arguments in expression")
 
-func ParseExpressionResult(expr object.Expression, blockArgs []string, args []string) object.Expression {
-	parsedExpr := expr
+func CheckExpression(operands []token.Token) error {
+	if len(operands) != 2 { //nolint:mnd // wontfix
+		return ErrTooFewArgumentsInExpression
+	}
 
-	parsedExpr.OperandA = object.SetFunctionParams(expr.OperandA, blockArgs, args)
-	parsedExpr.OperandB = object.SetFunctionParams(expr.OperandB, blockArgs, args)
+	return nil
+}
 
-	switch expr.Operation {
-	case 0:
-		parsedExpr.Result = parsedExpr.OperandA == parsedExpr.OperandB
-	case 1:
-		parsedExpr.Result = parsedExpr.OperandA != parsedExpr.OperandB
-	default:
-		parsedExpr.Result = true
+// CheckWhen parses the expression of a @(when) guard.
+func CheckWhen(source []token.Token) error {
+	if len(source) != 1 {
+		return errUnknownParameterInCaller
 	}
 
-	return parsedExpr
-}
+	pos := source[0].Pos
+	pos.Offset++
+	pos.Column++
 
-func ParseExpression(scanner *scanner.Scanner, currentBlock *object.StatefulFunctionBlock, operation int) error {
-	scanner.ReadNext()
-	operands := scanner.ScanParams()
-	scanner.ScanToEndOfLine()
+	_, err := expr.Parse(source[0].Value, pos)
 
-	if len(operands) != 2 { //nolint:mnd // wontfix
-		return ErrTooFewArgumentsInExpression
-	}
+	return err
+}
 
-	currentBlock.Expression = object.Expression{
-		OperandA:  object.SetEnvironmentVariables(operands[0]),
-		OperandB:  object.SetEnvironmentVariables(operands[1]),
-		Operation: operation,
-		Result:    true,
-	}
+// CheckPredicate validates a guard directive calling the expression function of the same name.
+func CheckPredicate(name string) func(args []token.Token) error {
+	return func(args []token.Token) error {
+		if want := expr.Functions[name].Args; len(args) != want {
+			return fmt.Errorf("%w in %s: expects %d, got %d", expr.ErrArgumentCount, name, want, len(args))
+		}
 
-	return nil
+		return nil
+	}
 }
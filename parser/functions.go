@@ -20,99 +20,245 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/ricochhet/gomake/object"
-	"github.com/ricochhet/gomake/scanner"
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/token"
 	"github.com/ricochhet/gomake/util"
 )
 
 var (
-	errUnknownPlatformIdentifier = errors.New("unknown platform identifier")
-	errUnknownParameterInCaller  = errors.New("unknown parameter in caller")
+	errUnknownParameterInCaller = errors.New("unknown parameter in caller")
+	errInvalidEnvironment       = errors.New("invalid environment variable, expects KEY=value")
+	errMissingPath              = errors.New("expects at least one path")
+	errUnknownCacheMode         = errors.New("unknown cache mode, expects \"mtime\" or \"hash\"")
+	errMtimeWithoutOutputs      = errors.New("mtime cache mode requires @(outputs)")
+	errInvalidParameter         = errors.New("invalid parameter name")
+	errDuplicateParameter       = errors.New("duplicate parameter")
+	errVariadicNotLast          = errors.New("only the last parameter can be variadic")
+	errRequiredAfterOptional    = errors.New("required parameter follows optional parameter")
+	errUnknownType              = errors.New("unknown parameter type")
+	errEmptyEnum                = errors.New("enum expects at least one value")
+	errDuplicateValue           = errors.New("duplicate enum value")
+	errUnexpectedArguments      = errors.New("directive expects no arguments")
+	errInvalidRetry             = errors.New("invalid retry, expects a number of attempts and an optional backoff such as \"2s\"")
+	errInvalidTimeout           = errors.New("invalid timeout, expects a positive duration such as \"90s\" or \"5m\"")
 )
 
-func ParseCommand(scanner *scanner.Scanner, currentBlock *object.StatefulFunctionBlock) error {
-	command := scanner.ScanToEndOfLine()
+//nolint:gochecknoglobals // wontfix
+var types = []string{ast.TypeString, ast.TypeInt, ast.TypeBool, ast.TypeEnum}
 
-	directory, err := object.SetBlockDirectory(*currentBlock)
-	if err != nil {
-		return err
+func CheckDirectory(identifier []token.Token) error {
+	if len(identifier) != 1 {
+		return errUnknownParameterInCaller
+	}
+
+	return nil
+}
+
+func CheckOperatingSystem(identifier []token.Token) error {
+	return checkIdentifier(identifier, util.CheckOS)
+}
+
+func CheckArchitecture(identifier []token.Token) error {
+	return checkIdentifier(identifier, util.CheckArch)
+}
+
+func CheckPlatform(identifier []token.Token) error {
+	return checkIdentifier(identifier, util.CheckPlatform)
+}
+
+func CheckVariableName(identifier []token.Token) error {
+	return checkIdentifier(identifier, util.CheckVariableName)
+}
+
+// checkIdentifier validates a single argument, unless it refers to a parameter and
+// can only be checked once the task runs.
+func checkIdentifier(identifier []token.Token, check func(string) error) error {
+	if len(identifier) != 1 {
+		return errUnknownParameterInCaller
 	}
 
-	os := object.SetBlockOperatingSystem(*currentBlock)
+	if strings.ContainsRune(identifier[0].Value, token.TokenLeftBracket) {
+		return nil
+	}
+
+	return check(identifier[0].Value)
+}
+
+func CheckEnvironment(variables []token.Token) error {
+	for _, variable := range variables {
+		if !strings.Contains(variable.Value, "=") {
+			return fmt.Errorf("%w: %q", errInvalidEnvironment, variable.Value)
+		}
+	}
+
+	return nil
+}
+
+func CheckPaths(paths []token.Token) error {
+	if len(paths) == 0 {
+		return errMissingPath
+	}
+
+	for _, path := range paths {
+		if path.Value == "" {
+			return errMissingPath
+		}
+	}
 
-	currentBlock.Commands = append(currentBlock.Commands, object.Command{
-		Command:     command,
-		OS:          os,
-		Directory:   directory,
-		Expression:  currentBlock.Expression,
-		Environment: currentBlock.Environment,
+	return nil
+}
+
+func CheckCacheMode(mode []token.Token) error {
+	if len(mode) != 1 || (mode[0].Value != "mtime" && mode[0].Value != "hash") {
+		return errUnknownCacheMode
+	}
+
+	return nil
+}
+
+func CheckTimeout(timeout []token.Token) error {
+	return checkIdentifier(timeout, func(value string) error {
+		if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+			return fmt.Errorf("%w, got %q", errInvalidTimeout, value)
+		}
+
+		return nil
 	})
+}
+
+func CheckNoArguments(args []token.Token) error {
+	if len(args) != 0 {
+		return errUnexpectedArguments
+	}
 
 	return nil
 }
 
-func ParseCaller(scanner *scanner.Scanner, currentBlock *object.StatefulFunctionBlock, blocks []object.StatefulFunctionBlock) error {
-	callerName, callerParams := scanner.ScanBlockWithParams()
-	if err := currentBlock.SetCallerBlock(blocks, callerName, callerParams); err != nil {
+func CheckRetry(retry []token.Token) error {
+	//nolint:mnd // wontfix
+	if len(retry) == 0 || len(retry) > 2 {
+		return errInvalidRetry
+	}
+
+	if err := checkIdentifier(retry[:1], checkAttempts); err != nil {
 		return err
 	}
 
+	if len(retry) == 1 {
+		return nil
+	}
+
+	return checkIdentifier(retry[1:], func(value string) error {
+		if duration, err := time.ParseDuration(value); err != nil || duration < 0 {
+			return fmt.Errorf("%w, got %q", errInvalidRetry, value)
+		}
+
+		return nil
+	})
+}
+
+func checkAttempts(value string) error {
+	if attempts, err := strconv.Atoi(value); err != nil || attempts < 1 {
+		return fmt.Errorf("%w, got %q", errInvalidRetry, value)
+	}
+
 	return nil
 }
 
-func ParseDirectory(scanner *scanner.Scanner, currentBlock *object.StatefulFunctionBlock, cwd string) error {
-	scanner.ReadNext()
-	identifier := scanner.ScanParams()
+// checkType validates the values of an enum and the default of a typed parameter.
+func checkType(param *ast.Param) error {
+	if param.Type == ast.TypeEnum && len(param.Values) == 0 {
+		return fmt.Errorf("%w, %q has none", errEmptyEnum, param.Name)
+	}
 
-	if len(identifier) != 1 {
-		return errUnknownParameterInCaller
+	for i, value := range param.Values {
+		if slices.Contains(param.Values[:i], value) {
+			return fmt.Errorf("%w %q", errDuplicateValue, value)
+		}
 	}
 
-	if identifier[0] == "" {
-		currentBlock.Directory = cwd
-	} else {
-		currentBlock.Directory = identifier[0]
+	if param.Default == "" {
+		return nil
 	}
 
-	currentBlock.Directory = object.SetEnvironmentVariables(currentBlock.Directory)
+	return param.Check(param.Default)
+}
+
+// checkArguments validates the arguments of a call against the types of the parameters of
+// callee. Arguments that refer to parameters or variables are checked when the task is called.
+func checkArguments(callee *ast.TaskDecl, args []string) error {
+	for i, arg := range args {
+		if strings.ContainsRune(arg, token.TokenLeftBracket) {
+			continue
+		}
+
+		if err := callee.Param(i).Check(arg); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func ParseOperatingSystem(scanner *scanner.Scanner, currentBlock *object.StatefulFunctionBlock) error {
-	scanner.ReadNext()
-	identifier := scanner.ScanParams()
+// CheckTask validates the directives that apply to a task as a whole.
+func CheckTask(task *ast.TaskDecl) error {
+	var outputs bool
 
-	if len(identifier) != 1 {
-		return errUnknownParameterInCaller
-	}
+	var mtime *ast.DirectiveStmt
 
-	if !slices.Contains(util.KnownOS, identifier[0]) && identifier[0] != "all" {
-		return errUnknownPlatformIdentifier
+	for _, stmt := range task.Body {
+		if directive, ok := stmt.(*ast.DirectiveStmt); ok {
+			switch {
+			case directive.Name == "outputs":
+				outputs = true
+			case directive.Name == "cache" && directive.Args[0] == "mtime":
+				mtime = directive
+			}
+		}
 	}
 
-	if identifier[0] == "" {
-		currentBlock.OS = "all"
-	} else {
-		currentBlock.OS = identifier[0]
+	if mtime != nil && !outputs {
+		return diagnostic.New(mtime.Pos, errMtimeWithoutOutputs)
 	}
 
 	return nil
 }
 
-func ParseEnvironment(scanner *scanner.Scanner, currentBlock *object.StatefulFunctionBlock) {
-	scanner.ReadNext()
-	variables := scanner.ScanParams()
-	scanner.ScanToEndOfLine()
+// CheckParam validates param against the parameters declared before it.
+func CheckParam(previous []*ast.Param, param *ast.Param) error {
+	if param.Name == "" || strings.ContainsAny(param.Name, "{} \t") {
+		return fmt.Errorf("%w %q", errInvalidParameter, param.Name)
+	}
 
-	envMap := util.SliceToMap(currentBlock.Environment)
-	varMap := util.SliceToMap(variables)
+	for _, other := range previous {
+		if other.Name == param.Name {
+			return fmt.Errorf("%w %q", errDuplicateParameter, param.Name)
+		}
+	}
+
+	if err := checkType(param); err != nil {
+		return err
+	}
 
-	for k, v := range varMap {
-		envMap[k] = v
+	if len(previous) == 0 {
+		return nil
 	}
 
-	currentBlock.Environment = util.MapToSlice(envMap)
+	last := previous[len(previous)-1]
+
+	switch {
+	case last.Variadic:
+		return fmt.Errorf("%w, %q follows %s...", errVariadicNotLast, param.Name, last.Name)
+	case last.Optional && !param.Optional && !param.Variadic:
+		return fmt.Errorf("%w, %q follows %q", errRequiredAfterOptional, param.Name, last.Name)
+	}
+
+	return nil
 }
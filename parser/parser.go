@@ -20,172 +20,519 @@ package parser
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"strings"
 
-	"github.com/ricochhet/gomake/object"
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/diagnostic"
 	"github.com/ricochhet/gomake/scanner"
 	"github.com/ricochhet/gomake/token"
+	"github.com/ricochhet/gomake/util"
 )
 
-var ErrTooFewArgumentsInBlock = errors.New("too few arguments in block")
+var (
+	ErrUnexpectedToken   = errors.New("unexpected")
+	ErrUnterminatedBlock = errors.New("missing closing bracket for task")
+)
+
+// Error is a file that failed to lex, parse or resolve. Err usually carries a
+// diagnostic with the position of the problem.
+type Error struct {
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
 
-func ParseBlock(block object.StatefulFunctionBlock) object.FunctionBlock {
-	return object.FunctionBlock{
-		Name:     block.Name,
-		Params:   block.Params,
-		Commands: block.Commands,
+// IgnorePrefix marks a command whose failures are ignored.
+const IgnorePrefix = "-"
+
+type parser struct {
+	tokens []token.Token
+	index  int
+	file   *ast.File
+}
+
+// ParseFile builds the syntax tree of a gomake file without evaluating it.
+func ParseFile(filename, text string) (*ast.File, error) {
+	file, err := parseFile(filename, text)
+	if err != nil {
+		return nil, &Error{Err: err}
 	}
+
+	return file, nil
 }
 
-func ParseStatefulBlock(block object.StatefulFunctionBlock, args []string) (object.StatefulFunctionBlock, error) {
-	parsedBlock := object.StatefulFunctionBlock{
-		Name:        block.Name,
-		Params:      block.Params,
-		Commands:    make([]object.Command, 0),
-		OS:          block.OS,
-		Directory:   block.Directory,
-		Expression:  block.Expression,
-		Environment: block.Environment,
+func parseFile(filename, text string) (*ast.File, error) {
+	tokens, err := scanner.Lex(filename, text)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(block.Params) != len(args) {
-		return object.StatefulFunctionBlock{}, ErrTooFewArgumentsInBlock
+	p := &parser{tokens: tokens, index: 0, file: &ast.File{
+		Name: filename, Vars: []*ast.VarDecl{}, Tasks: []*ast.TaskDecl{}, Comments: []*ast.Comment{},
+	}}
+
+	doc := make([]*ast.Comment, 0)
+
+	for p.peek().Kind != token.EOF {
+		switch tok := p.peek(); tok.Kind { //nolint:exhaustive // wontfix
+		case token.Newline:
+			p.next()
+		case token.Comment:
+			p.next()
+
+			comment := &ast.Comment{Pos: tok.Pos, Text: tok.Value}
+			if len(doc) != 0 && doc[len(doc)-1].Pos.Line != tok.Pos.Line-1 {
+				doc = doc[:0]
+			}
+
+			doc = append(doc, comment)
+			p.file.Comments = append(p.file.Comments, comment)
+		case token.Identifier:
+			if p.isDeclaration() {
+				p.next()
+
+				decl, err := p.parseVar()
+				if err != nil {
+					return nil, err
+				}
+
+				p.file.Vars = append(p.file.Vars, decl)
+
+				continue
+			}
+
+			task, err := p.parseTask()
+			if err != nil {
+				return nil, err
+			}
+
+			if len(doc) != 0 && doc[len(doc)-1].Pos.Line == task.Pos.Line-1 {
+				task.Doc = docText(doc)
+			}
+
+			p.file.Tasks = append(p.file.Tasks, task)
+		default:
+			return nil, p.unexpected(p.next(), token.Identifier)
+		}
 	}
 
-	for _, cmd := range block.Commands {
-		envParsedCmd, err := object.SetKeyValueVariables(object.SetFunctionParams(cmd.Command, block.Params, args), cmd.Environment)
-		if err != nil {
-			return object.StatefulFunctionBlock{}, err
+	if err := Resolve(p.file); err != nil {
+		return nil, err
+	}
+
+	return p.file, nil
+}
+
+func (p *parser) parseTask() (*ast.TaskDecl, error) {
+	name := p.next()
+
+	params, err := p.parseParams()
+	if err != nil {
+		return nil, err
+	}
+
+	needs, err := p.parseNeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(token.LeftBracket); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock(name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &ast.TaskDecl{Pos: name.Pos, Name: name.Value, Doc: "", Params: params, Needs: needs, Body: body}
+
+	return task, CheckTask(task)
+}
+
+// parseBlock parses statements up to and including the closing bracket of the task
+// named by owner, or of an if block inside it when nested is set.
+func (p *parser) parseBlock(owner token.Token, nested bool) ([]ast.Stmt, error) {
+	body := make([]ast.Stmt, 0)
+
+	for {
+		tok := p.next()
+
+		switch tok.Kind { //nolint:exhaustive // wontfix
+		case token.RightBracket:
+			return body, nil
+		case token.Newline:
+			continue
+		case token.Comment:
+			body = append(body, &ast.Comment{Pos: tok.Pos, Text: tok.Value})
+		case token.Command, token.Script:
+			body = append(body, command(tok, tok.Pos))
+		case token.Caller:
+			stmt, err := p.parseCaller(tok)
+			if err != nil {
+				return nil, err
+			}
+
+			if directive, ok := stmt.(*ast.DirectiveStmt); ok && nested && taskLevel[directive.Name] {
+				return nil, diagnostic.New(tok.Pos, fmt.Errorf("%w: %q", errTaskLevelDirective, directive.Name))
+			}
+
+			body = append(body, stmt)
+		case token.Identifier:
+			stmt, err := p.parseKeyword(tok, owner)
+			if err != nil {
+				return nil, err
+			}
+
+			body = append(body, stmt)
+		case token.EOF:
+			return nil, diagnostic.New(owner.Pos, fmt.Errorf("%w %s", ErrUnterminatedBlock, owner.Value))
+		default:
+			return nil, p.unexpected(tok, token.Command)
 		}
+	}
+}
 
-		parsedBlock.Commands = append(parsedBlock.Commands, object.Command{
-			OS:          cmd.OS,
-			Command:     envParsedCmd,
-			Directory:   cmd.Directory,
-			Expression:  ParseExpressionResult(cmd.Expression, block.Params, args),
-			Environment: object.SetArrayFunctionParams(cmd.Environment, block.Params, args),
-		})
+// parseKeyword parses a statement of a task body that starts with the identifier tok.
+func (p *parser) parseKeyword(tok token.Token, owner token.Token) (ast.Stmt, error) {
+	switch {
+	case tok.Value == "if":
+		return p.parseIf(tok, owner)
+	case tok.Value == "var" && p.peek().Kind == token.Identifier:
+		return p.parseVar()
 	}
 
-	parsedBlock.Params = []string{}
+	return nil, p.unexpected(tok, token.Command)
+}
 
-	return parsedBlock, nil
+func (p *parser) isDeclaration() bool {
+	return p.peek().Value == "var" && p.tokens[p.index+1].Kind == token.Identifier
 }
 
-//nolint:gocognit,gocyclo,cyclop,funlen // wontfix
-func ParseText(text string) ([]object.StatefulFunctionBlock, error) {
-	blocks := []object.StatefulFunctionBlock{}
+// parseVar parses NAME = "value" following the var keyword.
+func (p *parser) parseVar() (*ast.VarDecl, error) {
+	name, err := p.expect(token.Identifier)
+	if err != nil {
+		return nil, err
+	}
 
-	var currentBlock *object.StatefulFunctionBlock
+	if _, err := p.expect(token.Assign); err != nil {
+		return nil, err
+	}
 
-	cwd, err := os.Getwd()
+	value, err := p.expect(token.String)
 	if err != nil {
 		return nil, err
 	}
 
-	scanner := scanner.NewScanner(text)
-	function := false
+	return &ast.VarDecl{Pos: name.Pos, Name: name.Value, Value: value.Value}, p.endOfLine()
+}
 
-	for scanner.CurrentRune != 0 {
-		scanner.SkipWhitespace()
+// parseParams parses an optional parameter list. A parameter is a name, quoted or not,
+// followed by ? if it is optional or ... if it is variadic, then by an optional type
+// such as : int, and by = "value" if it has a default.
+func (p *parser) parseParams() ([]*ast.Param, error) {
+	params := make([]*ast.Param, 0)
 
-		if scanner.CurrentRune == token.TokenComment {
-			for scanner.CurrentRune != token.TokenNewLine && scanner.CurrentRune != 0 {
-				scanner.ReadNext()
-			}
+	if p.peek().Kind != token.LeftParen {
+		return params, nil
+	}
 
-			continue
+	p.next()
+
+	for {
+		p.skipNewlines()
+
+		if p.peek().Kind == token.RightParen {
+			p.next()
+			return params, nil
+		}
+
+		param, err := p.parseParam()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := CheckParam(params, param); err != nil {
+			return nil, diagnostic.New(param.Pos, err)
 		}
 
-		if scanner.CurrentRune == token.TokenLeftBracket {
-			scanner.ReadNext()
+		params = append(params, param)
 
-			function = true
+		p.skipNewlines()
 
+		if p.peek().Kind == token.Delimiter {
+			p.next()
 			continue
 		}
 
-		if scanner.CurrentRune == token.TokenRightBracket {
-			scanner.ReadNext()
+		if _, err := p.expect(token.RightParen); err != nil {
+			return nil, err
+		}
 
-			if currentBlock != nil {
-				blocks = append(blocks, *currentBlock)
-				currentBlock = nil
-			}
+		return params, nil
+	}
+}
 
-			function = false
+func (p *parser) parseParam() (*ast.Param, error) {
+	name := p.next()
+	if name.Kind != token.Identifier && name.Kind != token.String {
+		return nil, p.unexpected(name, token.Identifier)
+	}
 
-			continue
+	param := &ast.Param{Pos: name.Pos, Name: name.Value, Type: "", Values: []string{}, Default: "", Optional: false, Variadic: false}
+
+	switch p.peek().Kind { //nolint:exhaustive // wontfix
+	case token.Optional:
+		p.next()
+
+		param.Optional = true
+	case token.Ellipsis:
+		p.next()
+
+		param.Variadic = true
+	}
+
+	if p.peek().Kind == token.Colon {
+		p.next()
+
+		if err := p.parseType(param); err != nil {
+			return nil, err
 		}
+	}
 
-		if scanner.IsIndentifiable(scanner.CurrentRune) && !function {
-			blockName, blockParams := scanner.ScanBlockWithParams()
+	if p.peek().Kind == token.Assign && !param.Optional && !param.Variadic {
+		p.next()
 
-			currentBlock = &object.StatefulFunctionBlock{
-				Name:        blockName,
-				Params:      blockParams,
-				Commands:    make([]object.Command, 0),
-				OS:          "all",
-				Directory:   cwd,
-				Expression:  object.Expression{}, //nolint:exhaustruct // wontfix
-				Environment: make([]string, 0),
-			}
+		value, err := p.expect(token.String)
+		if err != nil {
+			return nil, err
+		}
+
+		param.Optional, param.Default = true, value.Value
+	}
+
+	return param, nil
+}
+
+// parseType parses the type of a parameter, one of string, int, bool or enum(value, ...).
+func (p *parser) parseType(param *ast.Param) error {
+	name, err := p.expect(token.Identifier)
+	if err != nil {
+		return err
+	}
+
+	switch name.Value {
+	case ast.TypeString, ast.TypeInt, ast.TypeBool:
+		param.Type = name.Value
+		return nil
+	case ast.TypeEnum:
+		param.Type = name.Value
+	default:
+		return diagnostic.New(name.Pos, fmt.Errorf("%w %q%s", errUnknownType, name.Value, util.Suggest(name.Value, types)))
+	}
 
+	if _, err := p.expect(token.LeftParen); err != nil {
+		return err
+	}
+
+	for {
+		p.skipNewlines()
+
+		switch tok := p.next(); tok.Kind { //nolint:exhaustive // wontfix
+		case token.RightParen:
+			return nil
+		case token.Identifier, token.String:
+			param.Values = append(param.Values, tok.Value)
+		default:
+			return p.unexpected(tok, token.Identifier)
+		}
+
+		p.skipNewlines()
+
+		if p.peek().Kind == token.Delimiter {
+			p.next()
 			continue
 		}
 
-		if currentBlock == nil || !function {
-			scanner.ReadNext()
+		if _, err := p.expect(token.RightParen); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// parseNeeds parses an optional needs(task, task("arg"), ...) clause.
+func (p *parser) parseNeeds() ([]*ast.CallStmt, error) {
+	needs := make([]*ast.CallStmt, 0)
+
+	if tok := p.peek(); tok.Kind != token.Identifier || tok.Value != "needs" {
+		return needs, nil
+	}
+
+	p.next()
+
+	if _, err := p.expect(token.LeftParen); err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipNewlines()
+
+		if p.peek().Kind == token.RightParen {
+			p.next()
+			return needs, nil
+		}
+
+		name, err := p.expect(token.Identifier)
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := p.parseOptionalStrings()
+		if err != nil {
+			return nil, err
+		}
+
+		needs = append(needs, &ast.CallStmt{Pos: name.Pos, Name: name.Value, Args: values(args)})
+
+		p.skipNewlines()
+
+		if p.peek().Kind == token.Delimiter {
+			p.next()
 			continue
 		}
 
-		switch scanner.CurrentRune {
-		case token.TokenCaller:
-			scanner.ReadNext()
-
-			switch scanner.CurrentRune {
-			case token.TokenLeftParen:
-				switch scanner.Peek(0) {
-				case 'c':
-					if err := CdCaller(scanner, currentBlock, cwd); err != nil {
-						return nil, err
-					}
-				case 'o':
-					if err := OsCaller(scanner, currentBlock); err != nil {
-						return nil, err
-					}
-				case 'e':
-					if err := EqCaller(scanner, currentBlock); err != nil {
-						return nil, err
-					}
-
-					if err := EnvCaller(scanner, currentBlock); err != nil {
-						return nil, err
-					}
-				case 'n':
-					if err := NeqCaller(scanner, currentBlock); err != nil {
-						return nil, err
-					}
-				default:
-					scanner.ScanToEndOfLine()
-				}
+		if _, err := p.expect(token.RightParen); err != nil {
+			return nil, err
+		}
 
-				continue
-			default:
-				if err := ParseCaller(scanner, currentBlock, blocks); err != nil {
-					return nil, err
-				}
+		return needs, nil
+	}
+}
 
-				continue
-			}
-		default:
-			if err := ParseCommand(scanner, currentBlock); err != nil {
-				return nil, err
-			}
+// parseOptionalStrings parses a parenthesized list of strings if one follows.
+func (p *parser) parseOptionalStrings() ([]token.Token, error) {
+	if p.peek().Kind != token.LeftParen {
+		return nil, nil
+	}
+
+	p.next()
 
+	return p.parseStrings()
+}
+
+// parseStrings parses comma separated strings up to and including the closing parenthesis.
+func (p *parser) parseStrings() ([]token.Token, error) {
+	list := make([]token.Token, 0)
+
+	for {
+		if p.peek().Kind == token.RightParen {
+			p.next()
+			return list, nil
+		}
+
+		str, err := p.expect(token.String)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, str)
+
+		if p.peek().Kind == token.Delimiter {
+			p.next()
 			continue
 		}
+
+		if _, err := p.expect(token.RightParen); err != nil {
+			return nil, err
+		}
+
+		return list, nil
+	}
+}
+
+// docText joins the text of comment lines, without the space following each #.
+func docText(comments []*ast.Comment) string {
+	lines := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		lines = append(lines, strings.TrimRight(strings.TrimPrefix(comment.Text, " "), " \t\r"))
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// command builds the statement of a command or script token at pos. A command starting
+// with IgnorePrefix has its failures ignored, as in make.
+func command(tok token.Token, pos token.Position) *ast.CommandStmt {
+	stmt := &ast.CommandStmt{
+		Pos: pos, Text: tok.Value, Script: tok.Kind == token.Script, Capture: "", Timeout: "", Retry: []string{}, IgnoreErrors: false,
 	}
 
-	return blocks, nil
+	if text, ok := strings.CutPrefix(tok.Value, IgnorePrefix); ok && !stmt.Script {
+		stmt.Text, stmt.IgnoreErrors = strings.TrimLeft(text, " \t"), true
+	}
+
+	return stmt
+}
+
+func values(tokens []token.Token) []string {
+	values := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		values = append(values, tok.Value)
+	}
+
+	return values
+}
+
+func (p *parser) endOfLine() error {
+	switch tok := p.peek(); tok.Kind { //nolint:exhaustive // wontfix
+	case token.Newline:
+		p.next()
+	case token.Comment, token.EOF:
+	default:
+		return p.unexpected(tok, token.Newline)
+	}
+
+	return nil
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().Kind == token.Newline {
+		p.next()
+	}
+}
+
+func (p *parser) peek() token.Token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) next() token.Token {
+	tok := p.tokens[p.index]
+	if tok.Kind != token.EOF {
+		p.index++
+	}
+
+	return tok
+}
+
+func (p *parser) expect(kind token.Kind) (token.Token, error) {
+	tok := p.next()
+	if tok.Kind != kind {
+		return tok, p.unexpected(tok, kind)
+	}
+
+	return tok, nil
+}
+
+func (p *parser) unexpected(tok token.Token, expected token.Kind) error {
+	return diagnostic.New(tok.Pos, fmt.Errorf("%w %s, expected %s", ErrUnexpectedToken, tok, expected))
 }
@@ -0,0 +1,177 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ricochhet/gomake/ast"
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/scanner"
+	"github.com/ricochhet/gomake/util"
+)
+
+var (
+	ErrUnknownTask   = errors.New("unknown task")
+	ErrDuplicateTask = errors.New("duplicate task")
+	ErrCallCycle     = errors.New("call cycle detected")
+	ErrArgumentCount = errors.New("wrong number of arguments")
+
+	ErrDuplicateVariable = errors.New("duplicate variable")
+	ErrVariableCycle     = errors.New("variable cycle detected")
+)
+
+// Resolve checks that every task called from the file is declared and that
+// no task calls itself through a chain of callers.
+func Resolve(file *ast.File) error {
+	if err := resolveVars(file.Vars); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(file.Tasks))
+	tasks := make(map[string]*ast.TaskDecl, len(file.Tasks))
+
+	for _, task := range file.Tasks {
+		if previous, ok := tasks[task.Name]; ok {
+			return diagnostic.New(task.Pos, fmt.Errorf("%w %q, first declared at %s", ErrDuplicateTask, task.Name, previous.Pos))
+		}
+
+		names = append(names, task.Name)
+		tasks[task.Name] = task
+	}
+
+	for _, task := range file.Tasks {
+		for _, call := range Calls(task) {
+			callee, ok := tasks[call.Name]
+			if !ok {
+				return diagnostic.New(call.Pos, fmt.Errorf("%w %q%s", ErrUnknownTask, call.Name, util.Suggest(call.Name, names)))
+			}
+
+			if !callee.Accepts(len(call.Args)) {
+				return diagnostic.New(call.Pos,
+					fmt.Errorf("%w in call to %s: expects %s, got %d", ErrArgumentCount, callee.Name, callee.Expects(), len(call.Args)))
+			}
+
+			if err := checkArguments(callee, call.Args); err != nil {
+				return diagnostic.New(call.Pos, err)
+			}
+		}
+	}
+
+	visited := make(map[string]bool, len(file.Tasks))
+
+	for _, task := range file.Tasks {
+		if err := visit(task, tasks, visited, []string{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Calls returns the tasks task depends on, its needs followed by the call statements in its body.
+func Calls(task *ast.TaskDecl) []*ast.CallStmt {
+	calls := append(make([]*ast.CallStmt, 0, len(task.Needs)), task.Needs...)
+
+	return appendCalls(calls, task.Body)
+}
+
+func appendCalls(calls []*ast.CallStmt, body []ast.Stmt) []*ast.CallStmt {
+	for _, stmt := range body {
+		switch stmt := stmt.(type) {
+		case *ast.CallStmt:
+			calls = append(calls, stmt)
+		case *ast.IfStmt:
+			calls = appendCalls(appendCalls(calls, stmt.Then), stmt.Else)
+		}
+	}
+
+	return calls
+}
+
+func visit(task *ast.TaskDecl, tasks map[string]*ast.TaskDecl, visited map[string]bool, chain []string) error {
+	if visited[task.Name] {
+		return nil
+	}
+
+	chain = append(chain, task.Name)
+
+	for _, call := range Calls(task) {
+		for i, name := range chain {
+			if name == call.Name {
+				cycle := append(chain[i:len(chain):len(chain)], call.Name)
+				return diagnostic.New(call.Pos, fmt.Errorf("%w: %s", ErrCallCycle, strings.Join(cycle, " -> ")))
+			}
+		}
+
+		if err := visit(tasks[call.Name], tasks, visited, chain); err != nil {
+			return err
+		}
+	}
+
+	visited[task.Name] = true
+
+	return nil
+}
+
+// resolveVars rejects duplicate file level variables and variables that refer to themselves.
+func resolveVars(decls []*ast.VarDecl) error {
+	vars := make(map[string]*ast.VarDecl, len(decls))
+
+	for _, decl := range decls {
+		if previous, ok := vars[decl.Name]; ok {
+			return diagnostic.New(decl.Pos, fmt.Errorf("%w %q, first declared at %s", ErrDuplicateVariable, decl.Name, previous.Pos))
+		}
+
+		vars[decl.Name] = decl
+	}
+
+	for _, decl := range decls {
+		if err := visitVar(decl, vars, []string{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func visitVar(decl *ast.VarDecl, vars map[string]*ast.VarDecl, chain []string) error {
+	chain = append(chain, decl.Name)
+
+	for _, name := range scanner.ScanReferences(decl.Value) {
+		ref, ok := vars[name]
+		if !ok {
+			continue
+		}
+
+		if i := slices.Index(chain, name); i != -1 {
+			cycle := append(chain[i:len(chain):len(chain)], name)
+			return diagnostic.New(vars[name].Pos, fmt.Errorf("%w: %s", ErrVariableCycle, strings.Join(cycle, " -> ")))
+		}
+
+		if err := visitVar(ref, vars, chain); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/parser"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		text   string
+		err    error
+		msg    string
+		line   int
+		column int
+	}{
+		{
+			name: "forward reference",
+			text: "a() {\n    @b\n}\nb() {\n    echo b\n}\n",
+		},
+		{
+			name: "unknown task with suggestion",
+			text: "build() {\n    @tset\n}\ntest() {\n    echo test\n}\n",
+			err:  parser.ErrUnknownTask, msg: `unknown task "tset", did you mean "test"?`, line: 2, column: 5,
+		},
+		{
+			name: "unknown task without suggestion",
+			text: "build() {\n    @deploy\n}\ntest() {\n    echo test\n}\n",
+			err:  parser.ErrUnknownTask, msg: `unknown task "deploy"`, line: 2, column: 5,
+		},
+		{
+			name: "unknown task in needs",
+			text: "build() needs(gen) {\n    echo build\n}\n",
+			err:  parser.ErrUnknownTask, msg: `unknown task "gen"`, line: 1, column: 15,
+		},
+		{
+			name: "self call",
+			text: "a() {\n    @a\n}\n",
+			err:  parser.ErrCallCycle, msg: "a -> a", line: 2, column: 5,
+		},
+		{
+			name: "call cycle",
+			text: "a() {\n    @b\n}\nb() {\n    echo b\n    @a\n}\n",
+			err:  parser.ErrCallCycle, msg: "a -> b -> a", line: 6, column: 5,
+		},
+		{
+			name: "call cycle inside an if block",
+			text: "a() {\n    @b\n}\nb() {\n    if os == \"linux\" {\n        @c\n    }\n}\nc() {\n    @a\n}\n",
+			err:  parser.ErrCallCycle, msg: "a -> b -> c -> a", line: 10, column: 5,
+		},
+		{
+			name: "duplicate task",
+			text: "a() {\n}\na() {\n}\n",
+			err:  parser.ErrDuplicateTask, msg: "first declared at", line: 3, column: 1,
+		},
+		{
+			name: "argument count",
+			text: "a() {\n    @b(\"x\", \"y\")\n}\nb(x) {\n    echo {x}\n}\n",
+			err:  parser.ErrArgumentCount, msg: "expects 1, got 2", line: 2, column: 5,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := parser.ParseFile("test.gomake", test.text)
+		if test.err == nil {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+
+			continue
+		}
+
+		if !errors.Is(err, test.err) || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("%s: got %v, want %v containing %q", test.name, err, test.err, test.msg)
+			continue
+		}
+
+		var diag *diagnostic.Diagnostic
+		if !errors.As(err, &diag) || diag.Pos.Line != test.line || diag.Pos.Column != test.column {
+			t.Errorf("%s: got %v, want position %d:%d", test.name, err, test.line, test.column)
+		}
+	}
+}
@@ -19,56 +19,178 @@
 package process
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ricochhet/gomake/object"
 	"github.com/ricochhet/gomake/util"
 )
 
-var ErrInvalidPlatformArchitecture = errors.New("invalid platform architecture")
+// DefaultGrace is how long an interrupted command has to exit before it is killed.
+const DefaultGrace = 10 * time.Second
 
-func Exec(commands []object.Command) error {
-	var shell, flag string
+// ExitError is a command that ran and exited with a non-zero status.
+type ExitError struct {
+	Command string
+	Code    int
+	Err     error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command %q failed with %v", e.Command, e.Err)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// InterruptError is a command stopped because its context ended, either because
+// gomake received a signal or because another task failed.
+type InterruptError struct {
+	Command string
+	Err     error
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("interrupted %q: %v", e.Command, e.Err)
+}
+
+func (e *InterruptError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is a command stopped because it ran out of time. Scope names what set
+// the timeout when it is not the command itself, such as the task it runs in.
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+	Scope   string
+}
+
+func (e *TimeoutError) Error() string {
+	msg := "timed out after " + e.Timeout.String()
+	if e.Command != "" {
+		msg = strconv.Quote(e.Command) + " " + msg
+	}
+
+	if e.Scope != "" {
+		msg += ", set by " + e.Scope
+	}
+
+	return msg
+}
+
+// SignalError is the cause of a context cancelled because gomake received Signal.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return "received " + e.Signal.String()
+}
+
+// Shell returns the shell commands are run with and the flag that passes it a command.
+func Shell() (string, string) {
 	if runtime.GOOS == "windows" {
-		shell = "cmd"
-		flag = "/C"
-	} else {
-		shell = "bash"
-		flag = "-c"
+		return "cmd", "/C"
 	}
 
-	for _, cmd := range commands {
-		if runtime.GOOS != cmd.OS && cmd.OS != "all" {
-			return ErrInvalidPlatformArchitecture
-		}
+	return "bash", "-c"
+}
 
-		if !cmd.Expression.Result {
-			return nil
-		}
+func Exec(ctx context.Context, cmd object.Command) error {
+	shell, flag := Shell()
 
+	if cmd.Script {
+		fmt.Printf("gomake: executing script in directory: %s\n%s\n", cmd.Directory, cmd.Command)
+	} else {
 		fmt.Printf("gomake: executing command: %s in directory: %s\n", cmd.Command, cmd.Directory)
+	}
 
-		args := util.StringToArgs(cmd.Command)
-		args = append([]string{flag}, args...)
+	args, cleanup, err := ShellArgs(flag, cmd)
+	if err != nil {
+		return err
+	}
 
-		command := exec.Command(shell, args...)
-		command.Stdout = os.Stdout
-		command.Stderr = os.Stderr
-		command.Dir = cmd.Directory
+	defer cleanup()
 
-		if len(cmd.Environment) != 0 {
-			command.Env = append(command.Env, command.Environ()...)
-			command.Env = append(command.Env, cmd.Environment...)
-		}
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeoutCause(ctx, cmd.Timeout, &TimeoutError{Command: "", Timeout: cmd.Timeout, Scope: ""})
+		defer cancel()
+	}
+
+	command := exec.CommandContext(ctx, shell, args...)
+	command.Stdout = os.Stdout
+	if cmd.Stdout != nil {
+		command.Stdout = cmd.Stdout
+	}
+	command.Stderr = os.Stderr
+	command.Dir = cmd.Directory
+
+	if len(cmd.Environment) != 0 {
+		command.Env = append(command.Env, command.Environ()...)
+		command.Env = append(command.Env, cmd.Environment...)
+	}
+
+	kill := interrupt(ctx, command, cmd.Grace)
 
-		if err := command.Run(); err != nil {
-			return err
+	err = command.Run()
+	if err != nil && ctx.Err() != nil {
+		kill()
+
+		var timeoutErr *TimeoutError
+		if errors.As(context.Cause(ctx), &timeoutErr) {
+			return &TimeoutError{Command: cmd.Command, Timeout: timeoutErr.Timeout, Scope: timeoutErr.Scope}
 		}
+
+		return &InterruptError{Command: cmd.Command, Err: context.Cause(ctx)}
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Command: cmd.Command, Code: exitErr.ExitCode(), Err: err}
+	}
+
+	return err
+}
+
+// ShellArgs builds the shell arguments for cmd. bash receives the command as a single
+// script, cmd receives split arguments or, for scripts, a temporary batch file.
+func ShellArgs(flag string, cmd object.Command) ([]string, func(), error) {
+	if runtime.GOOS != "windows" {
+		return []string{flag, cmd.Command}, func() {}, nil
+	}
+
+	if !cmd.Script {
+		return append([]string{flag}, util.StringToArgs(cmd.Command)...), func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "gomake-*.cmd")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	script := "@echo off\r\n" + strings.ReplaceAll(cmd.Command, "\n", "\r\n") + "\r\n"
+	if _, err := file.WriteString(script); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+
+		return nil, nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return nil, nil, err
 	}
 
-	return nil
+	return []string{flag, file.Name()}, func() { _ = os.Remove(file.Name()) }, nil
 }
@@ -0,0 +1,110 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// DefaultBackoff is the wait after the first failed attempt when @(retry) sets none.
+const DefaultBackoff = time.Second
+
+// maxBackoff caps the doubling of the wait between attempts.
+const maxBackoff = time.Hour
+
+// Retry is how many times a command or task is attempted, and how long to wait after
+// the first failed attempt. The wait doubles after every further failure.
+type Retry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// RetryError is a command or task that failed every attempt, with the error of each.
+type RetryError struct {
+	Name string
+	Errs []error
+}
+
+func (e *RetryError) Error() string {
+	var msg strings.Builder
+
+	fmt.Fprintf(&msg, "%s failed after %d attempts", e.Name, len(e.Errs))
+
+	for i, err := range e.Errs {
+		fmt.Fprintf(&msg, "\n\tattempt %d: %s", i+1, strings.ReplaceAll(err.Error(), "\n", "\n\t"))
+	}
+
+	return msg.String()
+}
+
+func (e *RetryError) Unwrap() []error {
+	return e.Errs
+}
+
+// Delay returns how long to wait after the nth failed attempt: Backoff doubled for every
+// earlier failure, plus up to half of that again so that retries do not run in lockstep.
+func (r Retry) Delay(n int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < n && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, maxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay + rand.N(delay/2+1) //nolint:gosec // wontfix
+}
+
+// Do calls run until it succeeds or every attempt failed, logging each failed attempt
+// of name. It stops waiting as soon as ctx ends.
+func (r Retry) Do(ctx context.Context, name string, run func() error) error {
+	if r.Attempts <= 1 {
+		return run()
+	}
+
+	errs := make([]error, 0, r.Attempts)
+
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, err)
+
+		if attempt == r.Attempts || ctx.Err() != nil {
+			return &RetryError{Name: name, Errs: errs}
+		}
+
+		delay := r.Delay(attempt)
+		fmt.Printf("gomake: attempt %d of %d of %s failed, retrying in %s: %v\n", attempt, r.Attempts, name, delay.Round(time.Millisecond), err)
+
+		select {
+		case <-ctx.Done():
+			return &RetryError{Name: name, Errs: append(errs, context.Cause(ctx))}
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package process
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// interrupt starts command in its own process group, so that a signal reaches every
+// process it started. When ctx ends the group receives the signal that cancelled ctx,
+// or SIGTERM, and the command is killed if it has not exited once grace has passed.
+// The returned function kills what is left of the group after the command exited.
+func interrupt(ctx context.Context, command *exec.Cmd, grace time.Duration) func() {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true} //nolint:exhaustruct // wontfix
+	command.WaitDelay = grace
+	command.Cancel = func() error {
+		signal := syscall.SIGTERM
+
+		var signalErr *SignalError
+		if errors.As(context.Cause(ctx), &signalErr) {
+			if sig, ok := signalErr.Signal.(syscall.Signal); ok {
+				signal = sig
+			}
+		}
+
+		return syscall.Kill(-command.Process.Pid, signal)
+	}
+
+	return func() {
+		if command.Process != nil {
+			_ = syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package process
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// interrupt kills command when ctx ends. Windows has no signals to forward, so the
+// command is killed right away and grace only bounds the wait for its output.
+func interrupt(_ context.Context, command *exec.Cmd, grace time.Duration) func() {
+	command.WaitDelay = grace
+
+	return func() {}
+}
@@ -0,0 +1,353 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/token"
+)
+
+var (
+	ErrUnexpectedCharacter = errors.New("unexpected character")
+	ErrUnterminatedString  = errors.New("unterminated string")
+	ErrUnterminatedScript  = errors.New("unterminated script block, expects " + ScriptClose)
+	ErrMissingCondition    = errors.New("missing condition")
+)
+
+const (
+	ScriptOpen  = "<<<"
+	ScriptClose = ">>>"
+)
+
+//nolint:gochecknoglobals // wontfix
+var declaration = regexp.MustCompile(`^var[ \t]+[A-Za-z_][A-Za-z0-9_]*[ \t]*=`)
+
+//nolint:gochecknoglobals // wontfix
+var punctuation = map[rune]token.Kind{
+	token.TokenLeftParen:    token.LeftParen,
+	token.TokenRightParen:   token.RightParen,
+	token.TokenLeftBracket:  token.LeftBracket,
+	token.TokenRightBracket: token.RightBracket,
+	token.TokenDelimiter:    token.Delimiter,
+	token.TokenColon:        token.Colon,
+	token.TokenAssign:       token.Assign,
+	token.TokenOptional:     token.Optional,
+	token.TokenCaller:       token.Caller,
+}
+
+type Lexer struct {
+	scanner *Scanner
+	tokens  []token.Token
+	depth   int
+	caller  bool
+}
+
+// Lex splits text into tokens. Lines inside a task body that do not start with a caller,
+// a comment, a closing bracket, an if block or a variable declaration are emitted whole as
+// command tokens.
+func Lex(filename, text string) ([]token.Token, error) {
+	lexer := &Lexer{scanner: NewScanner(filename, text), tokens: make([]token.Token, 0), depth: 0, caller: false}
+
+	for lexer.scanner.CurrentRune != 0 {
+		if err := lexer.lexLine(); err != nil && lexer.scanner.Err == nil {
+			return nil, err
+		}
+	}
+
+	if lexer.scanner.Err != nil {
+		return nil, lexer.scanner.Err
+	}
+
+	lexer.emit(token.EOF, "", lexer.scanner.Pos())
+
+	return lexer.tokens, nil
+}
+
+func (l *Lexer) emit(kind token.Kind, value string, pos token.Position) {
+	l.tokens = append(l.tokens, token.Token{Kind: kind, Value: value, Pos: pos})
+}
+
+func (l *Lexer) skipBlank() {
+	for l.scanner.CurrentRune == token.TokenSpace || l.scanner.CurrentRune == token.TokenTab || l.scanner.CurrentRune == token.TokenReturn {
+		l.scanner.ReadNext()
+	}
+}
+
+func (l *Lexer) isCodeLine() bool {
+	switch l.scanner.CurrentRune {
+	case 0, token.TokenNewLine, token.TokenCaller, token.TokenComment, token.TokenRightBracket:
+		return true
+	}
+
+	return l.isConditional() || l.isDeclaration()
+}
+
+// isDeclaration reports whether the line declares a variable, as in var NAME = "value".
+func (l *Lexer) isDeclaration() bool {
+	return declaration.MatchString(l.scanner.Text[l.scanner.Position-l.scanner.Width:])
+}
+
+// isConditional reports whether the line opens an if block rather than running a shell
+// command that happens to start with if, by requiring it to end in an opening bracket.
+func (l *Lexer) isConditional() bool {
+	line := l.scanner.Text[l.scanner.Position-l.scanner.Width:]
+	if end := strings.IndexRune(line, token.TokenNewLine); end != -1 {
+		line = line[:end]
+	}
+
+	rest, ok := strings.CutPrefix(line, "if")
+	if !ok || rest == "" || l.scanner.IsIndentifiable(rune(rest[0])) {
+		return false
+	}
+
+	return strings.HasSuffix(strings.TrimRight(rest, " \t\r"), string(token.TokenLeftBracket))
+}
+
+func (l *Lexer) lexLine() error {
+	l.skipBlank()
+
+	if l.depth > 0 && !l.isCodeLine() {
+		return l.lexCommand()
+	}
+
+	for {
+		l.skipBlank()
+
+		pos := l.scanner.Pos()
+		r := l.scanner.CurrentRune
+
+		switch {
+		case r == 0:
+			return nil
+		case r == token.TokenNewLine:
+			l.scanner.ReadNext()
+			l.emit(token.Newline, "", pos)
+
+			l.caller = false
+
+			return nil
+		case r == token.TokenComment:
+			l.scanner.ReadNext()
+			l.emit(token.Comment, l.scanner.ScanToEndOfLine(), pos)
+		case r == token.TokenQuote:
+			if err := l.lexString(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(l.scanner.Text[l.scanner.Position-l.scanner.Width:], token.TokenEllipsis):
+			for range token.TokenEllipsis {
+				l.scanner.ReadNext()
+			}
+
+			l.emit(token.Ellipsis, token.TokenEllipsis, pos)
+		case l.scanner.IsIndentifiable(r):
+			identifier := l.scanner.ScanIdentifier()
+			l.emit(token.Identifier, identifier, pos)
+
+			if identifier == "if" && l.depth > 0 {
+				if err := l.lexCondition(pos); err != nil {
+					return err
+				}
+			}
+		default:
+			kind, ok := punctuation[r]
+			if !ok {
+				return diagnostic.New(pos, fmt.Errorf("%w %q", ErrUnexpectedCharacter, r))
+			}
+
+			switch kind { //nolint:exhaustive // wontfix
+			case token.LeftBracket:
+				l.depth++
+			case token.RightBracket:
+				l.depth = max(l.depth-1, 0)
+			}
+
+			l.scanner.ReadNext()
+			l.emit(kind, string(r), pos)
+
+			if kind == token.RightParen && l.caller && l.depth > 0 {
+				return l.lexTrailingCommand()
+			}
+
+			if kind == token.Caller {
+				l.caller = true
+			}
+		}
+	}
+}
+
+// lexTrailingCommand emits the text following a caller on the same line as a command,
+// as in @(capture:"HASH") git rev-parse HEAD.
+func (l *Lexer) lexTrailingCommand() error {
+	l.caller = false
+	l.skipBlank()
+
+	switch l.scanner.CurrentRune {
+	case 0, token.TokenNewLine, token.TokenComment:
+		return nil
+	}
+
+	return l.lexCommand()
+}
+
+// lexCondition emits everything between if and the opening bracket ending the line as
+// a single condition, which is parsed by package expr.
+func (l *Lexer) lexCondition(at token.Position) error {
+	l.skipBlank()
+
+	pos := l.scanner.Pos()
+	line := l.scanner.Text[l.scanner.Position-l.scanner.Width:]
+
+	if end := strings.IndexRune(line, token.TokenNewLine); end != -1 {
+		line = line[:end]
+	}
+
+	line = strings.TrimRight(line, " \t\r")
+	if !strings.HasSuffix(line, string(token.TokenLeftBracket)) {
+		return diagnostic.New(at, fmt.Errorf("%w, if expects a condition followed by %q", ErrMissingCondition, token.TokenLeftBracket))
+	}
+
+	condition := strings.TrimRight(line[:len(line)-1], " \t")
+	if condition == "" {
+		return diagnostic.New(at, ErrMissingCondition)
+	}
+
+	for l.scanner.Position-l.scanner.Width < pos.Offset+len(condition) {
+		l.scanner.ReadNext()
+	}
+
+	l.emit(token.Condition, condition, pos)
+
+	return nil
+}
+
+// lexCommand emits the rest of the line as a command, joining lines that end in a backslash.
+// A line consisting of ScriptOpen starts a script block instead.
+func (l *Lexer) lexCommand() error {
+	pos := l.scanner.Pos()
+	line := l.scanLine()
+
+	if line == ScriptOpen {
+		return l.lexScript(pos)
+	}
+
+	var command strings.Builder
+
+	for strings.HasSuffix(line, string(token.TokenEscape)) && l.scanner.CurrentRune != 0 {
+		command.WriteString(strings.TrimRight(strings.TrimSuffix(line, string(token.TokenEscape)), " \t"))
+		command.WriteRune(token.TokenSpace)
+		l.scanner.ReadNext()
+		l.skipBlank()
+
+		line = l.scanLine()
+	}
+
+	command.WriteString(line)
+	l.emit(token.Command, command.String(), pos)
+
+	return nil
+}
+
+// lexScript emits every line up to ScriptClose as a single script, with the
+// indentation shared by all lines removed.
+func (l *Lexer) lexScript(pos token.Position) error {
+	lines := make([]string, 0)
+
+	for {
+		if l.scanner.CurrentRune == 0 {
+			return diagnostic.New(pos, ErrUnterminatedScript)
+		}
+
+		l.scanner.ReadNext()
+
+		line := strings.TrimRight(l.scanner.ReadWhile(func(r rune) bool { return r != token.TokenNewLine }), string(token.TokenReturn))
+		if strings.TrimSpace(line) == ScriptClose {
+			break
+		}
+
+		lines = append(lines, line)
+	}
+
+	l.emit(token.Script, strings.Join(dedent(lines), string(token.TokenNewLine)), pos)
+
+	return nil
+}
+
+func (l *Lexer) scanLine() string {
+	return strings.TrimRight(l.scanner.ReadWhile(func(r rune) bool { return r != token.TokenNewLine }), " \t\r")
+}
+
+func dedent(lines []string) []string {
+	prefix := ""
+	first := true
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, strings.TrimPrefix(line, prefix))
+	}
+
+	return result
+}
+
+func (l *Lexer) lexString() error {
+	pos := l.scanner.Pos()
+	l.scanner.ReadNext()
+
+	var value strings.Builder
+
+	for l.scanner.CurrentRune != token.TokenQuote {
+		switch l.scanner.CurrentRune {
+		case 0, token.TokenNewLine:
+			return diagnostic.New(pos, ErrUnterminatedString)
+		case token.TokenEscape:
+			if l.scanner.Peek() == token.TokenQuote {
+				l.scanner.ReadNext()
+			}
+		}
+
+		value.WriteRune(l.scanner.CurrentRune)
+		l.scanner.ReadNext()
+	}
+
+	l.scanner.ReadNext()
+	l.emit(token.String, value.String(), pos)
+
+	return nil
+}
@@ -0,0 +1,324 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package scanner_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ricochhet/gomake/diagnostic"
+	"github.com/ricochhet/gomake/scanner"
+	"github.com/ricochhet/gomake/token"
+)
+
+// lexed is a token reduced to what the tests compare.
+type lexed struct {
+	kind   token.Kind
+	value  string
+	line   int
+	column int
+}
+
+// commandTest lexes text and expects the command and script tokens in want.
+type commandTest struct {
+	name string
+	text string
+	want []string
+}
+
+// errorTest lexes text and expects err at line and column.
+type errorTest struct {
+	text   string
+	err    error
+	line   int
+	column int
+}
+
+func lex(t *testing.T, text string) []lexed {
+	t.Helper()
+
+	tokens, err := scanner.Lex("test.gomake", text)
+	if err != nil {
+		t.Fatalf("Lex(%q): %v", text, err)
+	}
+
+	result := make([]lexed, 0, len(tokens))
+	for _, tok := range tokens {
+		result = append(result, lexed{kind: tok.Kind, value: tok.Value, line: tok.Pos.Line, column: tok.Pos.Column})
+	}
+
+	return result
+}
+
+// commands returns the values of the command and script tokens in tokens.
+func commands(tokens []lexed) []string {
+	values := make([]string, 0)
+
+	for _, tok := range tokens {
+		if tok.kind == token.Command || tok.kind == token.Script {
+			values = append(values, tok.value)
+		}
+	}
+
+	return values
+}
+
+func testCommands(t *testing.T, tests []commandTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		if got := commands(lex(t, test.text)); !slices.Equal(got, test.want) {
+			t.Errorf("%s: commands = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func testErrors(t *testing.T, tests []errorTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		_, err := scanner.Lex("test.gomake", test.text)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Lex(%q) = %v, want %v", test.text, err, test.err)
+			continue
+		}
+
+		var diag *diagnostic.Diagnostic
+		if !errors.As(err, &diag) || diag.Pos.Line != test.line || diag.Pos.Column != test.column {
+			t.Errorf("Lex(%q) = %v, want position %d:%d", test.text, err, test.line, test.column)
+		}
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	t.Parallel()
+
+	got := lex(t, "build(a) {\n    go build\n}\n")
+	want := []lexed{
+		{token.Identifier, "build", 1, 1},
+		{token.LeftParen, "(", 1, 6},
+		{token.Identifier, "a", 1, 7},
+		{token.RightParen, ")", 1, 8},
+		{token.LeftBracket, "{", 1, 10},
+		{token.Newline, "", 1, 11},
+		{token.Command, "go build", 2, 5},
+		{token.Newline, "", 2, 13},
+		{token.RightBracket, "}", 3, 1},
+		{token.Newline, "", 3, 2},
+		{token.EOF, "", 4, 1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d tokens %v, want %d", len(got), got, len(want))
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("token %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	t.Parallel()
+
+	testErrors(t, []errorTest{
+		{"a() {\n    @(cd:\"dir)\n}\n", scanner.ErrUnterminatedString, 2, 10},
+		{"a$() {\n}\n", scanner.ErrUnexpectedCharacter, 1, 2},
+		{"a() {\n}\n\n  %\n", scanner.ErrUnexpectedCharacter, 4, 3},
+	})
+}
+
+func TestLexByteOrderMark(t *testing.T) {
+	t.Parallel()
+
+	tokens, err := scanner.Lex("test.gomake", "\uFEFFbuild() {\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first := tokens[0]; first.Value != "build" || first.Pos.Line != 1 || first.Pos.Column != 1 || first.Pos.Offset != 3 {
+		t.Errorf("first token = %+v, want build at 1:1, offset 3", first)
+	}
+}
+
+func TestLexMultibyteColumns(t *testing.T) {
+	t.Parallel()
+
+	got := lex(t, "a() {\n    @(cd:\"déjà\") ✓\n}\n")
+
+	for _, tok := range got {
+		if tok.kind == token.Command && (tok.value != "✓" || tok.column != 18) {
+			t.Errorf("command = %+v, want ✓ at column 18", tok)
+		}
+	}
+}
+
+func TestLexInvalidEncoding(t *testing.T) {
+	t.Parallel()
+
+	testErrors(t, []errorTest{
+		{"build() {\n    echo \xff\n}\n", scanner.ErrInvalidEncoding, 2, 10},
+		{"\xfe", scanner.ErrInvalidEncoding, 1, 1},
+		{"build\xc3(", scanner.ErrInvalidEncoding, 1, 6},
+	})
+}
+
+func TestLexMultiline(t *testing.T) {
+	t.Parallel()
+
+	testCommands(t, []commandTest{
+		{
+			name: "continuation",
+			text: "a() {\n    go build \\\n        -o bin/a \\\n        ./cmd/a\n}\n",
+			want: []string{"go build -o bin/a ./cmd/a"},
+		},
+		{
+			name: "continuation at end of file",
+			text: "a() {\n    echo a \\",
+			want: []string{"echo a \\"},
+		},
+		{
+			name: "script",
+			text: "a() {\n    <<<\n        if true; then\n            echo a\n        fi\n    >>>\n    echo b\n}\n",
+			want: []string{"if true; then\n    echo a\nfi", "echo b"},
+		},
+		{
+			name: "script with blank lines",
+			text: "a() {\n    <<<\n\t\techo a\n\n\t\techo b\n    >>>\n}\n",
+			want: []string{"echo a\n\necho b"},
+		},
+	})
+}
+
+func TestLexUnterminatedScript(t *testing.T) {
+	t.Parallel()
+
+	testErrors(t, []errorTest{
+		{"a() {\n    <<<\n    echo a\n", scanner.ErrUnterminatedScript, 2, 5},
+	})
+}
+
+func TestLexCondition(t *testing.T) {
+	t.Parallel()
+
+	tokens := lex(t, "a() {\n    if os == \"linux\" && !exists('x') {\n        echo a\n"+
+		"    } else if arch(\"arm64\") {\n        echo b\n    }\n}\n")
+	conditions := make([]lexed, 0)
+
+	for _, tok := range tokens {
+		if tok.kind == token.Condition {
+			conditions = append(conditions, tok)
+		}
+	}
+
+	want := []lexed{
+		{token.Condition, `os == "linux" && !exists('x')`, 2, 8},
+		{token.Condition, `arch("arm64")`, 4, 15},
+	}
+
+	if !slices.Equal(conditions, want) {
+		t.Errorf("conditions = %+v, want %+v", conditions, want)
+	}
+
+	if got := commands(tokens); !slices.Equal(got, []string{"echo a", "echo b"}) {
+		t.Errorf("commands = %q", got)
+	}
+}
+
+func TestLexShellIf(t *testing.T) {
+	t.Parallel()
+
+	testCommands(t, []commandTest{
+		{
+			name: "shell if",
+			text: "a() {\n    if [ -f go.mod ]; then go build; fi\n    ifconfig\n    if x {}\n}\n",
+			want: []string{"if [ -f go.mod ]; then go build; fi", "ifconfig", "if x {}"},
+		},
+		{
+			name: "shell if inside a block",
+			text: "a() {\n    if os == \"linux\" {\n        if true; then echo a; fi\n    }\n}\n",
+			want: []string{"if true; then echo a; fi"},
+		},
+	})
+}
+
+func TestLexMissingCondition(t *testing.T) {
+	t.Parallel()
+
+	testErrors(t, []errorTest{
+		{"a() {\n    if {\n}\n", scanner.ErrMissingCondition, 2, 5},
+		{"a() {\n    if\t {\n}\n", scanner.ErrMissingCondition, 2, 5},
+	})
+}
+
+func TestLexDeclaration(t *testing.T) {
+	t.Parallel()
+
+	tokens := lex(t, "var A = \"1\"\na() {\n    var B=\"${A}\"\n    variable=1\n    var\n}\n")
+	want := []lexed{
+		{token.Identifier, "var", 1, 1},
+		{token.Identifier, "A", 1, 5},
+		{token.Assign, "=", 1, 7},
+		{token.String, "1", 1, 9},
+	}
+
+	if !slices.Equal(tokens[:len(want)], want) {
+		t.Errorf("tokens = %+v, want %+v", tokens[:len(want)], want)
+	}
+
+	declared := slices.ContainsFunc(tokens, func(tok lexed) bool {
+		return tok.kind == token.String && tok.value == "${A}" && tok.line == 3 && tok.column == 11
+	})
+	if !declared {
+		t.Errorf("declaration in task body not lexed: %+v", tokens)
+	}
+
+	if got := commands(tokens); !slices.Equal(got, []string{"variable=1", "var"}) {
+		t.Errorf("commands = %q", got)
+	}
+}
+
+func TestLexCapture(t *testing.T) {
+	t.Parallel()
+
+	testCommands(t, []commandTest{
+		{
+			name: "trailing command",
+			text: "a() {\n    @(capture:\"HASH\") git rev-parse HEAD\n    @(capture:\"EMPTY\")\n    echo ${HASH}\n}\n",
+			want: []string{"git rev-parse HEAD", "echo ${HASH}"},
+		},
+		{
+			name: "trailing comment",
+			text: "a() {\n    @(capture:\"HASH\") # not a command\n}\n",
+			want: []string{},
+		},
+		{
+			name: "trailing script",
+			text: "a() {\n    @(capture:\"OUT\") <<<\n        echo a\n    >>>\n}\n",
+			want: []string{"echo a"},
+		},
+	})
+
+	tokens := lex(t, "a() {\n    @(capture:\"HASH\")   git rev-parse HEAD\n}\n")
+	if i := slices.IndexFunc(tokens, func(tok lexed) bool { return tok.kind == token.Command }); i < 0 || tokens[i].column != 25 {
+		t.Errorf("trailing command not at column 25: %+v", tokens)
+	}
+}
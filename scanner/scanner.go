@@ -19,47 +19,83 @@
 package scanner
 
 import (
+	"errors"
+	"regexp"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/ricochhet/gomake/diagnostic"
 	"github.com/ricochhet/gomake/token"
 )
 
+var ErrInvalidEncoding = errors.New("invalid UTF-8 encoding")
+
+const byteOrderMark = '\uFEFF'
+
+//nolint:gochecknoglobals // wontfix
+var reference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
 type Scanner struct {
+	Filename    string
 	Text        string
 	Position    int
+	Width       int
+	Line        int
+	Column      int
 	CurrentRune rune
+	Err         error
 }
 
-func NewScanner(text string) *Scanner {
-	s := &Scanner{Text: text, Position: 0, CurrentRune: 0}
+func NewScanner(filename, text string) *Scanner {
+	s := &Scanner{Filename: filename, Text: text, Position: 0, Width: 0, Line: 1, Column: 0, CurrentRune: 0, Err: nil}
 	s.ReadNext()
 
+	if s.CurrentRune == byteOrderMark {
+		s.ReadNext()
+		s.Column = 1
+	}
+
 	return s
 }
 
+// ReadNext decodes the next rune into CurrentRune. Scanning stops with Err set
+// when the text is not valid UTF-8.
 func (s *Scanner) ReadNext() {
-	if s.Position < len(s.Text) {
-		s.CurrentRune = rune(s.Text[s.Position])
-		s.Position++
-	} else {
+	if s.CurrentRune == token.TokenNewLine {
+		s.Line++
+		s.Column = 0
+	}
+
+	if s.Position >= len(s.Text) || s.Err != nil {
+		if s.Width != 0 || s.Column == 0 {
+			s.Column++
+		}
+
+		s.Width = 0
 		s.CurrentRune = 0
+
+		return
 	}
-}
 
-func (s *Scanner) ReadAhead(n int) {
-	if s.Position+n-1 < len(s.Text) {
-		s.CurrentRune = rune(s.Text[s.Position+n-1])
-		s.Position += n
-	} else {
+	r, width := utf8.DecodeRuneInString(s.Text[s.Position:])
+	if r == utf8.RuneError && width == 1 {
+		s.Column++
+		s.Width = 0
 		s.CurrentRune = 0
+		s.Err = diagnostic.New(token.Position{Filename: s.Filename, Offset: s.Position, Line: s.Line, Column: s.Column}, ErrInvalidEncoding)
+
+		return
 	}
+
+	s.CurrentRune = r
+	s.Position += width
+	s.Width = width
+	s.Column++
 }
 
-//nolint:lll // wontfix
-func (s *Scanner) SkipWhitespace() {
-	for s.CurrentRune != 0 && (s.CurrentRune == token.TokenSpace || s.CurrentRune == token.TokenTab || s.CurrentRune == token.TokenNewLine || s.CurrentRune == token.TokenReturn) {
-		s.ReadNext()
-	}
+// Pos reports the location of CurrentRune.
+func (s *Scanner) Pos() token.Position {
+	return token.Position{Filename: s.Filename, Offset: s.Position - s.Width, Line: s.Line, Column: s.Column}
 }
 
 func (s *Scanner) ReadWhile(predicate func(rune) bool) string {
@@ -82,118 +118,21 @@ func (s *Scanner) ScanIdentifier() string {
 	})
 }
 
-func (s *Scanner) ScanToUnescaped(target rune) string {
-	return s.ReadWhile(func(r rune) bool {
-		if s.Peek(-2) != token.TokenEscape && r == target {
-			return false
-		}
-
-		return true
-	})
-}
-
 func (s *Scanner) ScanToEndOfLine() string {
 	return s.ReadWhile(func(r rune) bool {
 		return r != token.TokenNewLine && r != token.TokenReturn && r != 0
 	})
 }
 
-func (s *Scanner) Peek(n int) rune {
-	peek := s.Position + n
-
-	if peek < len(s.Text) {
-		return rune(s.Text[peek])
+// Peek decodes the rune following CurrentRune without consuming it.
+func (s *Scanner) Peek() rune {
+	if s.Position >= len(s.Text) {
+		return 0
 	}
 
-	return 0
-}
-
-func (s *Scanner) PeekAhead(n int) string {
-	end := s.Position + n
-
-	if end > len(s.Text) {
-		end = len(s.Text)
-	}
+	r, _ := utf8.DecodeRuneInString(s.Text[s.Position:])
 
-	return s.Text[s.Position:end]
-}
-
-func (s *Scanner) ScanBlockWithParams() (string, []string) {
-	blockName := s.ScanIdentifier()
-
-	if s.CurrentRune == token.TokenLeftParen {
-		s.ReadNext()
-
-		params := make([]string, 0)
-
-		for {
-			if s.CurrentRune == token.TokenRightParen {
-				s.ReadNext()
-				break
-			}
-
-			s.SkipWhitespace()
-			s.ReadNext()
-
-			param := s.ScanToUnescaped(token.TokenQuote)
-
-			params = append(params, param)
-
-			if s.Peek(0) == token.TokenDelimiter {
-				s.ReadNext()
-			}
-
-			s.SkipWhitespace()
-
-			if s.CurrentRune == token.TokenDelimiter {
-				s.ReadNext()
-			} else {
-				s.ReadNext()
-				break
-			}
-		}
-
-		s.ReadNext()
-
-		return blockName, params
-	}
-
-	return blockName, nil
-}
-
-func (s *Scanner) ScanParams() []string {
-	params := make([]string, 0)
-
-	for {
-		if s.CurrentRune == token.TokenRightParen {
-			s.ReadNext()
-			break
-		}
-
-		s.SkipWhitespace()
-		s.ReadNext()
-
-		param := s.ScanToUnescaped(token.TokenQuote)
-
-		params = append(params, param)
-
-		if s.Peek(0) == token.TokenDelimiter {
-			s.ReadNext()
-		}
-
-		s.SkipWhitespace()
-
-		if s.CurrentRune == token.TokenDelimiter {
-			s.ReadNext()
-		} else {
-			s.ReadNext()
-			break
-		}
-	}
-
-	s.ReadNext()
-
-	return params
+	return r
 }
 
 func ScanVariables(text string) []string {
@@ -223,3 +162,41 @@ func ScanVariables(text string) []string {
 
 	return variables
 }
+
+// ScanReferences returns the names of the ${NAME} references in text.
+func ScanReferences(text string) []string {
+	names := make([]string, 0)
+
+	for _, match := range reference.FindAllStringSubmatch(text, -1) {
+		names = append(names, match[1])
+	}
+
+	return names
+}
+
+// ReplaceReferences replaces the ${NAME} references in text for which replace reports ok.
+func ReplaceReferences(text string, replace func(name string) (string, bool, error)) (string, error) {
+	var result strings.Builder
+
+	last := 0
+
+	for _, match := range reference.FindAllStringSubmatchIndex(text, -1) {
+		value, ok, err := replace(text[match[2]:match[3]])
+		if err != nil {
+			return "", err
+		}
+
+		if !ok {
+			continue
+		}
+
+		result.WriteString(text[last:match[0]])
+		result.WriteString(value)
+
+		last = match[1]
+	}
+
+	result.WriteString(text[last:])
+
+	return result.String(), nil
+}
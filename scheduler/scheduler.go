@@ -0,0 +1,244 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrDependencyFailed = errors.New("dependency failed")
+
+// Node is a unit of work in the graph. It runs at most once, after all of its dependencies.
+type Node struct {
+	Key  string
+	Deps []*Node
+	Err  error
+
+	run     func(ctx context.Context) error
+	started bool
+	done    chan struct{}
+}
+
+// Graph is a set of nodes keyed by name. Nodes can be added while the graph runs,
+// for example by tasks that call other tasks with dependencies.
+type Graph struct {
+	mu    sync.Mutex
+	nodes map[string]*Node
+}
+
+func NewGraph() *Graph {
+	return &Graph{mu: sync.Mutex{}, nodes: make(map[string]*Node)}
+}
+
+// Node returns the node registered under key, if any.
+func (g *Graph) Node(key string) *Node {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.nodes[key]
+}
+
+// Add registers a node under key, or returns the node already registered under it.
+func (g *Graph) Add(key string, deps []*Node, run func(ctx context.Context) error) *Node {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if node, ok := g.nodes[key]; ok {
+		return node
+	}
+
+	node := &Node{Key: key, Deps: deps, Err: nil, run: run, started: false, done: make(chan struct{})}
+	g.nodes[key] = node
+
+	return node
+}
+
+// Ensure runs node and its dependencies in the calling goroutine, or waits for
+// them if they were already started elsewhere.
+func (g *Graph) Ensure(ctx context.Context, node *Node) error {
+	if !g.claim(node) {
+		<-node.done
+		return node.Err
+	}
+
+	for _, dep := range node.Deps {
+		if err := g.Ensure(ctx, dep); err != nil {
+			node.finish(err)
+			return err
+		}
+	}
+
+	node.finish(node.run(ctx))
+
+	return node.Err
+}
+
+// Run executes root and everything it depends on, running up to jobs independent
+// nodes at once. The first failure cancels the remaining work unless keepGoing is set,
+// in which case only the nodes depending on a failed node are skipped.
+//
+//nolint:gocognit,cyclop // wontfix
+func (g *Graph) Run(ctx context.Context, root *Node, jobs int, keepGoing bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	order := postorder(root, make(map[*Node]bool), make([]*Node, 0))
+	finished := make(chan *Node)
+	scheduled := make(map[*Node]bool, len(order))
+	owned := make(map[*Node]bool, len(order))
+	errs := make([]error, 0)
+	active, running, stopped := 0, 0, false
+
+	for {
+		for _, node := range order {
+			if stopped || running >= max(jobs, 1) {
+				break
+			}
+
+			if scheduled[node] || !ready(node) {
+				continue
+			}
+
+			scheduled[node] = true
+			active++
+
+			if failed := failedDep(node); failed != nil {
+				if g.claim(node) {
+					node.finish(fmt.Errorf("%w: %s", ErrDependencyFailed, failed.Key))
+				}
+			} else if g.claim(node) {
+				owned[node] = true
+				running++
+
+				go func() {
+					node.finish(node.run(ctx))
+					finished <- node
+				}()
+
+				continue
+			}
+
+			go func() {
+				<-node.done
+				finished <- node
+			}()
+		}
+
+		if active == 0 {
+			break
+		}
+
+		node := <-finished
+		active--
+
+		if owned[node] {
+			running--
+		}
+
+		if node.Err != nil && !errors.Is(node.Err, ErrDependencyFailed) {
+			errs = append(errs, node.Err)
+
+			if !keepGoing {
+				stopped = true
+
+				cancel()
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	if !keepGoing {
+		return errs[0]
+	}
+
+	return errors.Join(errs...)
+}
+
+func (g *Graph) claim(node *Node) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if node.started {
+		return false
+	}
+
+	node.started = true
+
+	return true
+}
+
+func (n *Node) finish(err error) {
+	n.Err = err
+	close(n.done)
+}
+
+func (n *Node) finished() bool {
+	select {
+	case <-n.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func ready(node *Node) bool {
+	for _, dep := range node.Deps {
+		if !dep.finished() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func failedDep(node *Node) *Node {
+	for _, dep := range node.Deps {
+		if dep.Err != nil {
+			return dep
+		}
+	}
+
+	return nil
+}
+
+// Order lists the nodes reachable from root with dependencies before dependents.
+func Order(root *Node) []*Node {
+	return postorder(root, make(map[*Node]bool), make([]*Node, 0))
+}
+
+// postorder lists the nodes reachable from node with dependencies before dependents.
+func postorder(node *Node, seen map[*Node]bool, order []*Node) []*Node {
+	if seen[node] {
+		return order
+	}
+
+	seen[node] = true
+
+	for _, dep := range node.Deps {
+		order = postorder(dep, seen, order)
+	}
+
+	return append(order, node)
+}
@@ -0,0 +1,225 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ricochhet/gomake/scheduler"
+)
+
+var errFailed = errors.New("failed")
+
+func noop(context.Context) error { return nil }
+
+func TestRunBoundsJobs(t *testing.T) {
+	t.Parallel()
+
+	var running, peak atomic.Int32
+
+	g := scheduler.NewGraph()
+	deps := make([]*scheduler.Node, 0)
+
+	for i := range 8 {
+		deps = append(deps, g.Add("dep"+strconv.Itoa(i), nil, func(context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			return nil
+		}))
+	}
+
+	root := g.Add("root", deps, noop)
+
+	if err := g.Run(context.Background(), root, 2, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("%d nodes ran at once, want at most 2", got)
+	}
+}
+
+func TestRunSharedDependencyOnce(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+
+	g := scheduler.NewGraph()
+	shared := g.Add("shared", nil, func(context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	a := g.Add("a", []*scheduler.Node{shared}, noop)
+	b := g.Add("b", []*scheduler.Node{shared}, noop)
+
+	if g.Add("shared", nil, noop) != shared {
+		t.Error("Add registered a second node under the same key")
+	}
+
+	root := g.Add("root", []*scheduler.Node{a, b}, noop)
+
+	if err := g.Run(context.Background(), root, 4, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("shared dependency ran %d times, want 1", got)
+	}
+}
+
+func TestRunFailureCancelsSiblings(t *testing.T) {
+	t.Parallel()
+
+	var cancelled, later, rootRan atomic.Bool
+
+	g := scheduler.NewGraph()
+	failing := g.Add("failing", nil, func(context.Context) error {
+		return errFailed
+	})
+	sibling := g.Add("sibling", nil, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			cancelled.Store(true)
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	})
+	pending := g.Add("pending", nil, func(context.Context) error {
+		later.Store(true)
+		return nil
+	})
+	root := g.Add("root", []*scheduler.Node{failing, sibling, pending}, func(context.Context) error {
+		rootRan.Store(true)
+		return nil
+	})
+
+	if err := g.Run(context.Background(), root, 2, false); !errors.Is(err, errFailed) {
+		t.Fatalf("Run = %v, want %v", err, errFailed)
+	}
+
+	if !cancelled.Load() {
+		t.Error("running sibling was not cancelled")
+	}
+
+	if later.Load() || rootRan.Load() {
+		t.Error("nodes were started after the first failure")
+	}
+}
+
+func TestRunKeepGoingSkipsDependents(t *testing.T) {
+	t.Parallel()
+
+	var independent, dependent atomic.Bool
+
+	g := scheduler.NewGraph()
+	failing := g.Add("failing", nil, func(context.Context) error {
+		return errFailed
+	})
+	after := g.Add("after", []*scheduler.Node{failing}, func(context.Context) error {
+		dependent.Store(true)
+		return nil
+	})
+	other := g.Add("other", nil, func(ctx context.Context) error {
+		independent.Store(true)
+		return ctx.Err()
+	})
+	root := g.Add("root", []*scheduler.Node{after, other}, noop)
+
+	if err := g.Run(context.Background(), root, 1, true); !errors.Is(err, errFailed) {
+		t.Fatalf("Run = %v, want %v", err, errFailed)
+	}
+
+	if !independent.Load() || other.Err != nil {
+		t.Errorf("independent node did not run to completion: %v", other.Err)
+	}
+
+	if dependent.Load() {
+		t.Error("node depending on a failed node ran")
+	}
+
+	for _, node := range []*scheduler.Node{after, root} {
+		if !errors.Is(node.Err, scheduler.ErrDependencyFailed) {
+			t.Errorf("%s: Err = %v, want %v", node.Key, node.Err, scheduler.ErrDependencyFailed)
+		}
+	}
+}
+
+func TestEnsureRacesRun(t *testing.T) {
+	t.Parallel()
+
+	for range 50 {
+		var runs atomic.Int32
+
+		g := scheduler.NewGraph()
+		shared := g.Add("shared", nil, func(context.Context) error {
+			runs.Add(1)
+			time.Sleep(time.Millisecond)
+
+			return errFailed
+		})
+		root := g.Add("root", []*scheduler.Node{shared}, noop)
+
+		var wg sync.WaitGroup
+
+		errs := make([]error, 2)
+
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			errs[0] = g.Ensure(context.Background(), shared)
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			errs[1] = g.Run(context.Background(), root, 2, false)
+		}()
+
+		wg.Wait()
+
+		if got := runs.Load(); got != 1 {
+			t.Fatalf("shared node ran %d times, want 1", got)
+		}
+
+		for _, err := range errs {
+			if !errors.Is(err, errFailed) {
+				t.Fatalf("got %v, want %v", err, errFailed)
+			}
+		}
+	}
+}
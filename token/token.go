@@ -18,6 +18,8 @@
 
 package token
 
+import "fmt"
+
 const (
 	TokenLeftParen    = '('
 	TokenRightParen   = ')'
@@ -34,5 +36,108 @@ const (
 	TokenEscape       = '\\'
 	TokenQuote        = '"'
 	TokenColon        = ':'
+	TokenAssign       = '='
+	TokenOptional     = '?'
+	TokenEllipsis     = "..."
 	TokenString       = '%'
 )
+
+type Kind int
+
+const (
+	EOF Kind = iota
+	Newline
+	Comment
+	Identifier
+	String
+	Command
+	Script
+	Condition
+	Caller
+	LeftParen
+	RightParen
+	LeftBracket
+	RightBracket
+	Delimiter
+	Colon
+	Assign
+	Optional
+	Ellipsis
+)
+
+type Position struct {
+	Filename string `json:"filename"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type Token struct {
+	Kind  Kind     `json:"kind"`
+	Value string   `json:"value"`
+	Pos   Position `json:"pos"`
+}
+
+func (k Kind) String() string {
+	switch k {
+	case EOF:
+		return "end of file"
+	case Newline:
+		return "newline"
+	case Comment:
+		return "comment"
+	case Identifier:
+		return "identifier"
+	case String:
+		return "string"
+	case Command:
+		return "command"
+	case Script:
+		return "script"
+	case Condition:
+		return "condition"
+	case Caller:
+		return quote(TokenCaller)
+	case LeftParen:
+		return quote(TokenLeftParen)
+	case RightParen:
+		return quote(TokenRightParen)
+	case LeftBracket:
+		return quote(TokenLeftBracket)
+	case RightBracket:
+		return quote(TokenRightBracket)
+	case Delimiter:
+		return quote(TokenDelimiter)
+	case Colon:
+		return quote(TokenColon)
+	case Assign:
+		return quote(TokenAssign)
+	case Optional:
+		return quote(TokenOptional)
+	case Ellipsis:
+		return fmt.Sprintf("%q", TokenEllipsis)
+	}
+
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+func (p Position) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+func (t Token) String() string {
+	switch t.Kind { //nolint:exhaustive // wontfix
+	case Identifier, String, Command, Script, Condition, Comment:
+		return fmt.Sprintf("%s %q", t.Kind, t.Value)
+	}
+
+	return t.Kind.String()
+}
+
+func quote(r rune) string {
+	return fmt.Sprintf("%q", r)
+}
@@ -18,6 +18,39 @@
 
 package util
 
+import (
+	"runtime"
+	"strings"
+)
+
+// safeArg lists the characters an argument may consist of to be passed to a shell unquoted.
+const safeArg = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_@%+=:,./-"
+
+// QuoteArg quotes arg so that the shell of goos passes it to a command as a single argument.
+// bash takes it between single quotes, and cmd between double quotes, dropping the double
+// quotes inside arg since cmd has no way to escape them.
+func QuoteArg(goos, arg string) string {
+	if arg != "" && strings.Trim(arg, safeArg) == "" {
+		return arg
+	}
+
+	if goos == "windows" {
+		return `"` + strings.ReplaceAll(arg, `"`, "") + `"`
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// QuoteArgs quotes each of args for the shell gomake runs commands with and joins them with spaces.
+func QuoteArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, QuoteArg(runtime.GOOS, arg))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
 func StringToArgs(command string) []string {
 	var args []string
 
@@ -0,0 +1,62 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/ricochhet/gomake/util"
+)
+
+func TestQuoteArg(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		goos string
+		arg  string
+		want string
+	}{
+		{"linux", "./cmd/...", "./cmd/..."},
+		{"linux", "tags=integration", "tags=integration"},
+		{"linux", "", "''"},
+		{"linux", "./a b", "'./a b'"},
+		{"linux", "it's", `'it'\''s'`},
+		{"linux", "$HOME;rm", "'$HOME;rm'"},
+		{"darwin", "a*", "'a*'"},
+		{"windows", "C:/a", "C:/a"},
+		{"windows", "a b", `"a b"`},
+		{"windows", `say "hi"`, `"say hi"`},
+		{"windows", "", `""`},
+	}
+
+	for _, test := range tests {
+		if got := util.QuoteArg(test.goos, test.arg); got != test.want {
+			t.Errorf("QuoteArg(%q, %q) = %s, want %s", test.goos, test.arg, got, test.want)
+		}
+	}
+}
+
+func TestStringToArgs(t *testing.T) {
+	t.Parallel()
+
+	args := util.StringToArgs(`go test ` + util.QuoteArg("windows", "./a b") + ` ./c`)
+	if len(args) != 4 || args[2] != "./a b" || args[3] != "./c" {
+		t.Errorf("StringToArgs = %q, want the quoted argument kept whole", args)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const globStar = "**"
+
+// Glob returns the files matching pattern, leaving out directories. Besides the filepath.Match
+// syntax a "**" segment matches any number of directories, skipping directories starting with a dot.
+func Glob(pattern string) ([]string, error) {
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	if !strings.Contains(pattern, globStar) {
+		matches, err := filepath.Glob(filepath.FromSlash(pattern))
+
+		return slices.DeleteFunc(matches, isDir), err
+	}
+
+	segments := strings.Split(pattern, "/")
+	base := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+
+		base = append(base, segment)
+	}
+
+	root := strings.Join(base, "/")
+	if root == "" {
+		root = "."
+	}
+
+	matches := make([]string, 0)
+
+	err := filepath.WalkDir(filepath.FromSlash(root), func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if name != filepath.FromSlash(root) && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if MatchGlob(pattern, filepath.ToSlash(name)) {
+			matches = append(matches, name)
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	slices.Sort(matches)
+
+	return matches, nil
+}
+
+func isDir(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+// MatchGlob reports whether the slash separated name matches pattern.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path.Clean(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == globStar {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(name) == 0 {
+		return false
+	}
+
+	ok, err := path.Match(pattern[0], name[0])
+
+	return err == nil && ok && matchSegments(pattern[1:], name[1:])
+}
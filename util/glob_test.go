@@ -0,0 +1,116 @@
+/*
+ * gomake
+ * Copyright (C) 2024 gomake contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package util_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ricochhet/gomake/util"
+)
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"**", "a/b/c", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/main.go", true},
+		{"**/*.go", "a/b/main.txt", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "lib/a/main.go", false},
+		{"src/**", "src/a/b", true},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b", "a/x/c", false},
+		{"src/?.go", "src/a.go", true},
+		{"src/[ab].go", "src/c.go", false},
+		{"src/[", "src/[", false},
+		{"src/*.go", "./src/main.go", true},
+	}
+
+	for _, test := range tests {
+		if got := util.MatchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for _, name := range []string{"main.go", "README.md", "cmd/a/main.go", "cmd/b.go", ".git/hook.go", "cmd/.cache/x.go"} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*", []string{"README.md", "main.go"}},
+		{"cmd/*", []string{"cmd/b.go"}},
+		{"**/*.go", []string{"cmd/a/main.go", "cmd/b.go", "main.go"}},
+		{"cmd/**/main.go", []string{"cmd/a/main.go"}},
+		{"missing/**/*.go", []string{}},
+	}
+
+	for _, test := range tests {
+		matches, err := util.Glob(filepath.Join(dir, test.pattern))
+		if err != nil {
+			t.Errorf("Glob(%q): %v", test.pattern, err)
+			continue
+		}
+
+		got := make([]string, 0, len(matches))
+
+		for _, match := range matches {
+			rel, err := filepath.Rel(dir, match)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got = append(got, filepath.ToSlash(rel))
+		}
+
+		slices.Sort(got)
+
+		if !slices.Equal(got, test.want) {
+			t.Errorf("Glob(%q) = %q, want %q", test.pattern, got, test.want)
+		}
+	}
+}
@@ -18,7 +18,19 @@
 
 package util
 
-import "runtime"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnknownOS           = errors.New("unknown platform identifier")
+	ErrUnknownArch         = errors.New("unknown architecture")
+	ErrUnknownPlatform     = errors.New("unsupported platform")
+	ErrInvalidVariableName = errors.New("invalid environment variable name")
+)
 
 var KnownOS = []string{ //nolint:gochecknoglobals // wontfix
 	"aix",
@@ -41,10 +53,96 @@ var KnownOS = []string{ //nolint:gochecknoglobals // wontfix
 	"zos",
 }
 
-func Separator() string {
-	if runtime.GOOS == "windows" {
-		return ";"
+var KnownArch = []string{ //nolint:gochecknoglobals // wontfix
+	"386",
+	"amd64",
+	"arm",
+	"arm64",
+	"loong64",
+	"mips",
+	"mips64",
+	"mips64le",
+	"mipsle",
+	"ppc64",
+	"ppc64le",
+	"riscv64",
+	"s390x",
+	"wasm",
+}
+
+// KnownPlatforms are the GOOS/GOARCH pairs listed by go tool dist list.
+var KnownPlatforms = []string{ //nolint:gochecknoglobals // wontfix
+	"aix/ppc64",
+	"android/386", "android/amd64", "android/arm", "android/arm64",
+	"darwin/amd64", "darwin/arm64",
+	"dragonfly/amd64",
+	"freebsd/386", "freebsd/amd64", "freebsd/arm", "freebsd/arm64", "freebsd/riscv64",
+	"illumos/amd64",
+	"ios/amd64", "ios/arm64",
+	"js/wasm",
+	"linux/386", "linux/amd64", "linux/arm", "linux/arm64", "linux/loong64", "linux/mips", "linux/mips64",
+	"linux/mips64le", "linux/mipsle", "linux/ppc64", "linux/ppc64le", "linux/riscv64", "linux/s390x",
+	"netbsd/386", "netbsd/amd64", "netbsd/arm", "netbsd/arm64",
+	"openbsd/386", "openbsd/amd64", "openbsd/arm", "openbsd/arm64", "openbsd/ppc64", "openbsd/riscv64",
+	"plan9/386", "plan9/amd64", "plan9/arm",
+	"solaris/amd64",
+	"wasip1/wasm",
+	"windows/386", "windows/amd64", "windows/arm", "windows/arm64",
+}
+
+// CheckOS reports an error unless name is a GOOS value or "all".
+func CheckOS(name string) error {
+	if name == "all" || slices.Contains(KnownOS, name) {
+		return nil
+	}
+
+	return fmt.Errorf("%w %q%s", ErrUnknownOS, name, Suggest(name, KnownOS))
+}
+
+// CheckArch reports an error unless name is a GOARCH value or "all".
+func CheckArch(name string) error {
+	if name == "all" || slices.Contains(KnownArch, name) {
+		return nil
+	}
+
+	return fmt.Errorf("%w %q%s", ErrUnknownArch, name, Suggest(name, KnownArch))
+}
+
+// CheckPlatform reports an error unless name is a GOOS/GOARCH pair Go can build for, or "all".
+func CheckPlatform(name string) error {
+	if name == "all" || slices.Contains(KnownPlatforms, name) {
+		return nil
+	}
+
+	goos, goarch, ok := strings.Cut(name, "/")
+	if !ok {
+		return fmt.Errorf("%w %q, expects os/arch such as \"linux/arm64\"", ErrUnknownPlatform, name)
+	}
+
+	if err := CheckOS(goos); err != nil {
+		return err
+	}
+
+	if err := CheckArch(goarch); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w %q%s", ErrUnknownPlatform, name, Suggest(name, KnownPlatforms))
+}
+
+// CheckVariableName reports an error unless name can be used as an environment variable.
+func CheckVariableName(name string) error {
+	for i, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+
+		return fmt.Errorf("%w %q", ErrInvalidVariableName, name)
+	}
+
+	if name == "" {
+		return fmt.Errorf("%w %q", ErrInvalidVariableName, name)
 	}
 
-	return ":"
+	return nil
 }